@@ -0,0 +1,139 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"expvar"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	rpprof "runtime/pprof"
+	"strings"
+	"time"
+
+	"github.com/yacobolo/datastar-lit-examples/internal/datastar"
+)
+
+// startAdmin serves profiling and introspection endpoints on their own
+// listener, so they are only reachable where that address is. Nothing here
+// is mounted on the public mux, and metrics are served here instead of
+// there. The returned function closes the listener.
+func startAdmin(addr string, streams *datastar.Streams, metrics http.Handler) (stop func() error, err error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	expvar.Publish("sse_clients", expvar.Func(func() any { return streams.Clients() }))
+	expvar.Publish("goroutines", expvar.Func(func() any { return runtime.NumGoroutine() }))
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /{$}", adminIndex)
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	mux.Handle("GET /debug/vars", expvar.Handler())
+	mux.HandleFunc("GET /debug/goroutines", goroutineDump)
+	mux.Handle("GET /debug/sse", sseClients(streams))
+	mux.Handle("GET /metrics", metrics)
+
+	srv := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		if err := srv.Serve(ln); !errors.Is(err, http.ErrServerClosed) {
+			slog.Error("admin listener failed", "err", err)
+		}
+	}()
+	slog.Info("admin listening", "url", "http://"+ln.Addr().String()+"/")
+	return srv.Close, nil
+}
+
+// goroutineDump writes every goroutine's stack, as a crash would.
+func goroutineDump(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	rpprof.Lookup("goroutine").WriteTo(w, 2)
+}
+
+// sseClients lists the open SSE streams as JSON, or, for an EventSource,
+// streams the list every second.
+func sseClients(streams *datastar.Streams) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !strings.Contains(r.Header.Get("Accept"), "text/event-stream") {
+			w.Header().Set("Content-Type", "application/json")
+			enc := json.NewEncoder(w)
+			enc.SetIndent("", "  ")
+			enc.Encode(streams.Clients())
+			return
+		}
+
+		sse := datastar.NewSSE(w, r)
+		tick := time.NewTicker(time.Second)
+		defer tick.Stop()
+		for {
+			b, err := json.Marshal(streams.Clients())
+			if err != nil {
+				return
+			}
+			if err := sse.Send("clients", []string{string(b)}); err != nil {
+				return
+			}
+			select {
+			case <-sse.Done():
+				return
+			case <-tick.C:
+			}
+		}
+	}
+}
+
+func adminIndex(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write([]byte(adminPage))
+}
+
+const adminPage = `<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="UTF-8">
+<title>datastar-lit-examples admin</title>
+<style>
+body { font: 14px/1.5 system-ui, sans-serif; margin: 2rem; }
+table { border-collapse: collapse; }
+th, td { padding: .25rem .75rem; border-bottom: 1px solid #ddd; text-align: left; font-variant-numeric: tabular-nums; }
+</style>
+</head>
+<body>
+<h1>Admin</h1>
+<ul>
+<li><a href="/debug/pprof/">pprof</a> (<a href="/debug/pprof/heap?debug=1">heap</a>, <a href="/debug/pprof/profile?seconds=30">30s CPU profile</a>, <a href="/debug/pprof/trace?seconds=5">5s trace</a>)</li>
+<li><a href="/debug/goroutines">goroutine dump</a></li>
+<li><a href="/debug/vars">expvar</a></li>
+<li><a href="/debug/sse">SSE clients as JSON</a></li>
+<li><a href="/metrics">Prometheus metrics</a></li>
+</ul>
+<h2>SSE clients (<span id="count">0</span>)</h2>
+<table>
+<thead><tr><th>Request ID</th><th>Remote</th><th>Path</th><th>Open for</th><th>Events</th><th>Bytes</th></tr></thead>
+<tbody id="clients"></tbody>
+</table>
+<script>
+const tbody = document.getElementById('clients')
+new EventSource('/debug/sse').addEventListener('clients', e => {
+  const clients = JSON.parse(e.data)
+  document.getElementById('count').textContent = clients.length
+  tbody.replaceChildren(...clients.map(c => {
+    const tr = document.createElement('tr')
+    const age = Math.round((Date.now() - Date.parse(c.started)) / 1000) + 's'
+    for (const v of [c.id, c.remote, c.path, age, c.events, c.bytes]) {
+      tr.insertCell().textContent = v
+    }
+    return tr
+  }))
+})
+</script>
+</body>
+</html>
+`
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/yacobolo/datastar-lit-examples/internal/assets"
+)
+
+// build bundles the demo components and styles into demo/dist.
+func build(args []string) error {
+	fset := flag.NewFlagSet("build", flag.ExitOnError)
+	minify := fset.Bool("minify", os.Getenv("NODE_ENV") == "production", "minify output (default true when NODE_ENV=production)")
+	dir := fset.String("dir", ".", "repository root `dir` containing demo/")
+	if err := fset.Parse(args); err != nil {
+		return err
+	}
+
+	if err := assets.Build(assets.Options{Dir: *dir, Minify: *minify}); err != nil {
+		return err
+	}
+	fmt.Println("Demo components and CSS build complete")
+	return nil
+}
@@ -0,0 +1,70 @@
+// Command gen-signals generates the TypeScript interfaces and JSON Schema
+// for the demo's signal tree from the Go types in internal/signals, so the
+// Go side is the single source of truth for the props passed through
+// data-attr.
+//
+// Types opt in with directives in their doc comment:
+//
+//	//signals:ts flow-diagram   emit an interface into flow-diagram.types.ts
+//	//signals:schema            use this type as the JSON Schema root
+//
+// Named string types with constants become string literal unions, and
+// fields tagged omitempty become optional properties.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+)
+
+func main() {
+	pkgDir := flag.String("pkg", "internal/signals", "directory of the Go package to read")
+	tsDir := flag.String("ts", "demo/components", "directory to write <name>.types.ts files into")
+	schemaPath := flag.String("schema", "demo/signals.schema.json", "path of the JSON Schema to write")
+	check := flag.Bool("check", false, "report stale files instead of writing them")
+	flag.Parse()
+
+	log.SetFlags(0)
+	log.SetPrefix("gen-signals: ")
+
+	m, err := load(*pkgDir)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	files := map[string][]byte{}
+	ts, err := m.typescript()
+	if err != nil {
+		log.Fatal(err)
+	}
+	for name, src := range ts {
+		files[filepath.Join(*tsDir, name+".types.ts")] = src
+	}
+	schema, err := m.jsonSchema()
+	if err != nil {
+		log.Fatal(err)
+	}
+	files[*schemaPath] = schema
+
+	stale := 0
+	for _, path := range sortedKeys(files) {
+		src := files[path]
+		if *check {
+			if cur, err := os.ReadFile(path); err != nil || !bytes.Equal(cur, src) {
+				fmt.Fprintf(os.Stderr, "%s is out of date\n", path)
+				stale++
+			}
+			continue
+		}
+		if err := os.WriteFile(path, src, 0o644); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if stale > 0 {
+		log.Fatalf("%d generated file(s) out of date; run go generate ./...", stale)
+	}
+}
@@ -0,0 +1,219 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"maps"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// model is the subset of a Go package the generators understand.
+type model struct {
+	fset    *token.FileSet
+	structs map[string]*structDef
+	enums   map[string][]string // named string type -> constant values
+	order   []string            // struct names in declaration order
+	tsFile  map[string]string   // struct name -> //signals:ts target
+	root    string              // //signals:schema type
+}
+
+type structDef struct {
+	name   string
+	pos    token.Pos
+	fields []fieldDef
+}
+
+type fieldDef struct {
+	name     string // JSON name
+	typ      ast.Expr
+	optional bool
+}
+
+func load(dir string) (*model, error) {
+	m := &model{
+		fset:    token.NewFileSet(),
+		structs: map[string]*structDef{},
+		enums:   map[string][]string{},
+		tsFile:  map[string]string{},
+	}
+
+	paths, err := filepath.Glob(filepath.Join(dir, "*.go"))
+	if err != nil {
+		return nil, err
+	}
+	var files []*ast.File
+	for _, path := range paths {
+		if strings.HasSuffix(path, "_test.go") {
+			continue
+		}
+		f, err := parser.ParseFile(m.fset, path, nil, parser.ParseComments)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, f)
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no Go files in %s", dir)
+	}
+
+	// Types first, so constants can be matched to their named string type.
+	for _, f := range files {
+		for _, decl := range f.Decls {
+			gd, ok := decl.(*ast.GenDecl)
+			if !ok || gd.Tok != token.TYPE {
+				continue
+			}
+			for _, spec := range gd.Specs {
+				ts := spec.(*ast.TypeSpec)
+				doc := ts.Doc
+				if doc == nil && len(gd.Specs) == 1 {
+					doc = gd.Doc
+				}
+				if err := m.addType(ts, doc); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+	for _, f := range files {
+		for _, decl := range f.Decls {
+			if gd, ok := decl.(*ast.GenDecl); ok && gd.Tok == token.CONST {
+				m.addConsts(gd)
+			}
+		}
+	}
+
+	slices.SortFunc(m.order, func(a, b string) int {
+		return cmp.Compare(m.structs[a].pos, m.structs[b].pos)
+	})
+	return m, nil
+}
+
+func (m *model) addType(ts *ast.TypeSpec, doc *ast.CommentGroup) error {
+	name := ts.Name.Name
+	st, ok := ts.Type.(*ast.StructType)
+	if !ok {
+		if id, ok := ts.Type.(*ast.Ident); ok && id.Name == "string" {
+			m.enums[name] = nil
+		}
+		return nil
+	}
+
+	sd := &structDef{name: name, pos: ts.Pos()}
+	for _, field := range st.Fields.List {
+		if len(field.Names) == 0 {
+			return m.errorf(field.Pos(), "%s: embedded fields are not supported", name)
+		}
+		for _, ident := range field.Names {
+			if !ident.IsExported() {
+				continue
+			}
+			fd := fieldDef{name: ident.Name, typ: field.Type}
+			if field.Tag != nil {
+				tag, _ := strconv.Unquote(field.Tag.Value)
+				jsonName, opts, _ := strings.Cut(reflect.StructTag(tag).Get("json"), ",")
+				if jsonName == "-" {
+					continue
+				}
+				if jsonName != "" {
+					fd.name = jsonName
+				}
+				fd.optional = slices.Contains(strings.Split(opts, ","), "omitempty")
+			}
+			sd.fields = append(sd.fields, fd)
+		}
+	}
+	m.structs[name] = sd
+	m.order = append(m.order, name)
+
+	if doc == nil {
+		return nil
+	}
+	for _, c := range doc.List {
+		directive, arg, _ := strings.Cut(strings.TrimPrefix(c.Text, "//"), " ")
+		switch directive {
+		case "signals:ts":
+			if arg = strings.TrimSpace(arg); arg == "" {
+				return m.errorf(c.Pos(), "//signals:ts needs a file name")
+			}
+			m.tsFile[name] = arg
+		case "signals:schema":
+			if m.root != "" {
+				return m.errorf(c.Pos(), "//signals:schema on both %s and %s", m.root, name)
+			}
+			m.root = name
+		}
+	}
+	return nil
+}
+
+// addConsts records the values of typed string constants. Only constants
+// with an explicit type and a literal value count; iota is not used here.
+func (m *model) addConsts(gd *ast.GenDecl) {
+	for _, spec := range gd.Specs {
+		vs := spec.(*ast.ValueSpec)
+		ident, ok := vs.Type.(*ast.Ident)
+		if !ok {
+			continue
+		}
+		if _, isEnum := m.enums[ident.Name]; !isEnum {
+			continue
+		}
+		for _, v := range vs.Values {
+			if lit, ok := v.(*ast.BasicLit); ok && lit.Kind == token.STRING {
+				s, _ := strconv.Unquote(lit.Value)
+				m.enums[ident.Name] = append(m.enums[ident.Name], s)
+			}
+		}
+	}
+}
+
+func (m *model) errorf(pos token.Pos, format string, args ...any) error {
+	return fmt.Errorf("%s: %s", m.fset.Position(pos), fmt.Sprintf(format, args...))
+}
+
+// reachable returns the structs reachable from root in declaration order.
+func (m *model) reachable(root string) []string {
+	seen := map[string]bool{}
+	var walk func(ast.Expr)
+	walk = func(e ast.Expr) {
+		switch t := e.(type) {
+		case *ast.Ident:
+			if sd, ok := m.structs[t.Name]; ok && !seen[t.Name] {
+				seen[t.Name] = true
+				for _, f := range sd.fields {
+					walk(f.typ)
+				}
+			}
+		case *ast.ArrayType:
+			walk(t.Elt)
+		case *ast.MapType:
+			walk(t.Value)
+		case *ast.StarExpr:
+			walk(t.X)
+		}
+	}
+	walk(&ast.Ident{Name: root})
+	return slices.DeleteFunc(slices.Clone(m.order), func(name string) bool { return !seen[name] })
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	return slices.Sorted(maps.Keys(m))
+}
+
+func isNumber(name string) bool {
+	switch name {
+	case "int", "int8", "int16", "int32", "int64",
+		"uint", "uint8", "uint16", "uint32", "uint64",
+		"float32", "float64":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go/ast"
+)
+
+// jsonSchema renders a draft 2020-12 schema for the //signals:schema type,
+// with every struct it reaches under $defs.
+func (m *model) jsonSchema() ([]byte, error) {
+	if m.root == "" {
+		return nil, errors.New("no type has a //signals:schema directive")
+	}
+
+	defs := map[string]any{}
+	for _, name := range m.reachable(m.root) {
+		if name == m.root {
+			continue
+		}
+		obj, err := m.schemaObject(name)
+		if err != nil {
+			return nil, err
+		}
+		defs[name] = obj
+	}
+	root, err := m.schemaObject(m.root)
+	if err != nil {
+		return nil, err
+	}
+	root["$schema"] = "https://json-schema.org/draft/2020-12/schema"
+	root["title"] = m.root
+	root["$defs"] = defs
+
+	b, err := json.MarshalIndent(root, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(b, '\n'), nil
+}
+
+func (m *model) schemaObject(name string) (map[string]any, error) {
+	sd := m.structs[name]
+	props := map[string]any{}
+	required := []string{}
+	for _, f := range sd.fields {
+		s, err := m.schemaType(f.typ)
+		if err != nil {
+			return nil, m.errorf(f.typ.Pos(), "%s.%s: %v", name, f.name, err)
+		}
+		props[f.name] = s
+		if !f.optional {
+			required = append(required, f.name)
+		}
+	}
+	return map[string]any{
+		"type":       "object",
+		"properties": props,
+		"required":   required,
+	}, nil
+}
+
+func (m *model) schemaType(e ast.Expr) (map[string]any, error) {
+	switch t := e.(type) {
+	case *ast.Ident:
+		switch {
+		case t.Name == "string":
+			return map[string]any{"type": "string"}, nil
+		case t.Name == "bool":
+			return map[string]any{"type": "boolean"}, nil
+		case t.Name == "float32" || t.Name == "float64":
+			return map[string]any{"type": "number"}, nil
+		case isNumber(t.Name):
+			return map[string]any{"type": "integer"}, nil
+		case t.Name == "any":
+			return map[string]any{}, nil
+		}
+		if values, ok := m.enums[t.Name]; ok {
+			s := map[string]any{"type": "string"}
+			if len(values) > 0 {
+				s["enum"] = values
+			}
+			return s, nil
+		}
+		if _, ok := m.structs[t.Name]; ok {
+			return map[string]any{"$ref": "#/$defs/" + t.Name}, nil
+		}
+		return nil, fmt.Errorf("unsupported type %s", t.Name)
+	case *ast.ArrayType:
+		items, err := m.schemaType(t.Elt)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]any{"type": "array", "items": items}, nil
+	case *ast.MapType:
+		if k, ok := t.Key.(*ast.Ident); !ok || k.Name != "string" {
+			return nil, fmt.Errorf("map keys must be strings")
+		}
+		v, err := m.schemaType(t.Value)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]any{"type": "object", "additionalProperties": v}, nil
+	case *ast.StarExpr:
+		v, err := m.schemaType(t.X)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]any{"anyOf": []any{v, map[string]any{"type": "null"}}}, nil
+	case *ast.InterfaceType:
+		return map[string]any{}, nil
+	}
+	return nil, fmt.Errorf("unsupported type expression %T", e)
+}
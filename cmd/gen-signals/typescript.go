@@ -0,0 +1,136 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const tsHeader = "// Code generated by gen-signals from internal/signals. DO NOT EDIT.\n"
+
+// typescript renders one source file per //signals:ts target, keyed by the
+// target name.
+func (m *model) typescript() (map[string][]byte, error) {
+	byFile := map[string][]string{}
+	for _, name := range m.order {
+		if file, ok := m.tsFile[name]; ok {
+			byFile[file] = append(byFile[file], name)
+		}
+	}
+
+	out := map[string][]byte{}
+	for file, names := range byFile {
+		imports := map[string][]string{}
+		var body bytes.Buffer
+		for i, name := range names {
+			if i > 0 {
+				body.WriteByte('\n')
+			}
+			fmt.Fprintf(&body, "export interface %s {\n", name)
+			for _, f := range m.structs[name].fields {
+				t, err := m.tsType(f.typ, file, imports)
+				if err != nil {
+					return nil, m.errorf(f.typ.Pos(), "%s.%s: %v", name, f.name, err)
+				}
+				opt := ""
+				if f.optional {
+					opt = "?"
+				}
+				fmt.Fprintf(&body, "  %s%s: %s\n", tsKey(f.name), opt, t)
+			}
+			body.WriteString("}\n")
+		}
+
+		var buf bytes.Buffer
+		buf.WriteString(tsHeader)
+		buf.WriteByte('\n')
+		for _, from := range sortedKeys(imports) {
+			fmt.Fprintf(&buf, "import type { %s } from './%s.types.js'\n", strings.Join(imports[from], ", "), from)
+		}
+		if len(imports) > 0 {
+			buf.WriteByte('\n')
+		}
+		buf.Write(body.Bytes())
+		out[file] = buf.Bytes()
+	}
+	return out, nil
+}
+
+// tsType maps a Go type expression to TypeScript. Structs emitted into a
+// different file are imported from it.
+func (m *model) tsType(e ast.Expr, file string, imports map[string][]string) (string, error) {
+	switch t := e.(type) {
+	case *ast.Ident:
+		switch {
+		case t.Name == "string":
+			return "string", nil
+		case t.Name == "bool":
+			return "boolean", nil
+		case isNumber(t.Name):
+			return "number", nil
+		case t.Name == "any":
+			return "unknown", nil
+		}
+		if values, ok := m.enums[t.Name]; ok {
+			if len(values) == 0 {
+				return "string", nil
+			}
+			quoted := make([]string, len(values))
+			for i, v := range values {
+				quoted[i] = "'" + strings.ReplaceAll(v, "'", `\'`) + "'"
+			}
+			return strings.Join(quoted, " | "), nil
+		}
+		if _, ok := m.structs[t.Name]; ok {
+			target, ok := m.tsFile[t.Name]
+			if !ok {
+				return "", fmt.Errorf("%s has no //signals:ts directive", t.Name)
+			}
+			if target != file && !slices.Contains(imports[target], t.Name) {
+				imports[target] = append(imports[target], t.Name)
+			}
+			return t.Name, nil
+		}
+		return "", fmt.Errorf("unsupported type %s", t.Name)
+	case *ast.ArrayType:
+		elem, err := m.tsType(t.Elt, file, imports)
+		if err != nil {
+			return "", err
+		}
+		if strings.Contains(elem, " ") {
+			elem = "(" + elem + ")"
+		}
+		return elem + "[]", nil
+	case *ast.MapType:
+		if k, ok := t.Key.(*ast.Ident); !ok || k.Name != "string" {
+			return "", fmt.Errorf("map keys must be strings")
+		}
+		v, err := m.tsType(t.Value, file, imports)
+		if err != nil {
+			return "", err
+		}
+		return "Record<string, " + v + ">", nil
+	case *ast.StarExpr:
+		v, err := m.tsType(t.X, file, imports)
+		if err != nil {
+			return "", err
+		}
+		return v + " | null", nil
+	case *ast.InterfaceType:
+		return "unknown", nil
+	}
+	return "", fmt.Errorf("unsupported type expression %T", e)
+}
+
+var tsIdent = regexp.MustCompile(`^[A-Za-z_$][A-Za-z0-9_$]*$`)
+
+func tsKey(name string) string {
+	if tsIdent.MatchString(name) {
+		return name
+	}
+	return strconv.Quote(name)
+}
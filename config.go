@@ -0,0 +1,234 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/yacobolo/datastar-lit-examples/internal/static"
+)
+
+// config is the serve command's configuration. Values are layered, each
+// overriding the last: defaults, the JSON config file, SERVE_* environment
+// variables, then command-line flags.
+type config struct {
+	Addr   string   `json:"addr"`
+	Port   string   `json:"port"`
+	Root   string   `json:"root"`
+	States string   `json:"states"`
+	Flows  string   `json:"flows"`
+	Allow  []string `json:"allow"`
+	Deny   []string `json:"deny"`
+	Disk   bool     `json:"disk"`
+
+	TLSCert       string `json:"tlsCert"`
+	TLSKey        string `json:"tlsKey"`
+	TLSSelfSigned bool   `json:"tlsSelfSigned"`
+
+	ReadTimeout     duration `json:"readTimeout"`
+	WriteTimeout    duration `json:"writeTimeout"`
+	IdleTimeout     duration `json:"idleTimeout"`
+	ShutdownTimeout duration `json:"shutdownTimeout"`
+
+	LogFormat string `json:"logFormat"`
+	AdminAddr string `json:"adminAddr"`
+}
+
+func defaultConfig() config {
+	return config{
+		Port:   "8080",
+		Root:   ".",
+		States: "states",
+		Flows:  "flows",
+		Allow:  static.DefaultAllow,
+
+		ReadTimeout:     duration(30 * time.Second),
+		WriteTimeout:    duration(30 * time.Second),
+		IdleTimeout:     duration(2 * time.Minute),
+		ShutdownTimeout: duration(10 * time.Second),
+
+		LogFormat: "text",
+	}
+}
+
+// settings ties each setting to its flag and environment variable. Lists
+// are comma-separated in both.
+var settings = []struct {
+	name   string
+	env    string
+	usage  string
+	isBool bool
+	set    func(c *config, v string) error
+}{
+	{"addr", "SERVE_ADDR", "interface to bind; empty binds all", false, func(c *config, v string) error { c.Addr = v; return nil }},
+	{"port", "SERVE_PORT", "port to listen on (default 8080)", false, func(c *config, v string) error { c.Port = v; return nil }},
+	{"root", "SERVE_ROOT", "document root, e.g. _site (default .)", false, func(c *config, v string) error { c.Root = v; return nil }},
+	{"states", "SERVE_STATES", "dir of saved states for ?state= (default states)", false, func(c *config, v string) error { c.States = v; return nil }},
+	{"flows", "SERVE_FLOWS", "dir the flow diagram store saves documents in (default flows)", false, func(c *config, v string) error { c.Flows = v; return nil }},
+	{"allow", "SERVE_ALLOW", "globs under root that may be served (default index.html,demo/dist/**)", false, func(c *config, v string) error { c.Allow = splitList(v); return nil }},
+	{"deny", "SERVE_DENY", "globs under root that are never served, even if allowed", false, func(c *config, v string) error { c.Deny = splitList(v); return nil }},
+	{"disk", "SERVE_DISK", "serve root and states from disk even if the binary embeds the site", true, setBool(func(c *config) *bool { return &c.Disk })},
+	{"tls-cert", "SERVE_TLS_CERT", "PEM certificate `file`; enables TLS and HTTP/2", false, func(c *config, v string) error { c.TLSCert = v; return nil }},
+	{"tls-key", "SERVE_TLS_KEY", "PEM private key `file` for -tls-cert", false, func(c *config, v string) error { c.TLSKey = v; return nil }},
+	{"tls-self-signed", "SERVE_TLS_SELF_SIGNED", "serve TLS with a generated, cached localhost certificate", true, setBool(func(c *config) *bool { return &c.TLSSelfSigned })},
+	{"read-timeout", "SERVE_READ_TIMEOUT", "max time to read a request (default 30s)", false, setDuration(func(c *config) *duration { return &c.ReadTimeout })},
+	{"write-timeout", "SERVE_WRITE_TIMEOUT", "max time to write a response; SSE streams are exempt (default 30s)", false, setDuration(func(c *config) *duration { return &c.WriteTimeout })},
+	{"idle-timeout", "SERVE_IDLE_TIMEOUT", "how long idle keep-alive connections stay open (default 2m)", false, setDuration(func(c *config) *duration { return &c.IdleTimeout })},
+	{"shutdown-timeout", "SERVE_SHUTDOWN_TIMEOUT", "how long to wait for in-flight requests on SIGINT/SIGTERM (default 10s)", false, setDuration(func(c *config) *duration { return &c.ShutdownTimeout })},
+	{"log-format", "SERVE_LOG_FORMAT", "log output, text or json (default text)", false, func(c *config, v string) error { c.LogFormat = v; return nil }},
+	{"admin-addr", "SERVE_ADMIN_ADDR", "serve pprof, expvar and the SSE client view on this `address`, e.g. 127.0.0.1:6060; off when empty", false, func(c *config, v string) error { c.AdminAddr = v; return nil }},
+}
+
+func loadConfig(args []string) (config, error) {
+	cfg := defaultConfig()
+
+	type flagValue struct{ name, value string }
+	var flags []flagValue
+	fset := flag.NewFlagSet("serve", flag.ContinueOnError)
+	configPath := fset.String("config", os.Getenv("SERVE_CONFIG"), "read settings from JSON `file` (env SERVE_CONFIG)")
+	for _, s := range settings {
+		usage := fmt.Sprintf("%s (env %s)", s.usage, s.env)
+		record := func(v string) error {
+			flags = append(flags, flagValue{s.name, v})
+			return nil
+		}
+		if s.isBool {
+			fset.BoolFunc(s.name, usage, record)
+		} else {
+			fset.Func(s.name, usage, record)
+		}
+	}
+	if err := fset.Parse(args); err != nil {
+		return config{}, err
+	}
+	if fset.NArg() > 0 {
+		return config{}, fmt.Errorf("unexpected arguments: %v", fset.Args())
+	}
+
+	if *configPath != "" {
+		if err := cfg.readFile(*configPath); err != nil {
+			return config{}, err
+		}
+	}
+	for _, s := range settings {
+		if v, ok := os.LookupEnv(s.env); ok {
+			if err := s.set(&cfg, v); err != nil {
+				return config{}, fmt.Errorf("%s: %w", s.env, err)
+			}
+		}
+	}
+	for _, f := range flags {
+		for _, s := range settings {
+			if s.name == f.name {
+				if err := s.set(&cfg, f.value); err != nil {
+					return config{}, fmt.Errorf("-%s: %w", s.name, err)
+				}
+			}
+		}
+	}
+
+	if cfg.Port == "" {
+		return config{}, fmt.Errorf("port must not be empty")
+	}
+	if (cfg.TLSCert == "") != (cfg.TLSKey == "") {
+		return config{}, fmt.Errorf("-tls-cert and -tls-key must be set together")
+	}
+	if cfg.TLSCert != "" && cfg.TLSSelfSigned {
+		return config{}, fmt.Errorf("-tls-self-signed cannot be combined with -tls-cert")
+	}
+	if cfg.LogFormat != "text" && cfg.LogFormat != "json" {
+		return config{}, fmt.Errorf("-log-format must be text or json, not %q", cfg.LogFormat)
+	}
+	return cfg, nil
+}
+
+// readFile overlays the settings present in a JSON file. Unknown keys are
+// rejected so a typo does not silently fall back to a default.
+func (c *config) readFile(path string) error {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(c); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
+
+func (c config) listenAddr() string {
+	return net.JoinHostPort(c.Addr, c.Port)
+}
+
+func (c config) tls() bool {
+	return c.TLSCert != "" || c.TLSSelfSigned
+}
+
+// url is where a local browser can reach the server.
+func (c config) url() string {
+	host := c.Addr
+	if host == "" || host == "0.0.0.0" || host == "::" {
+		host = "localhost"
+	}
+	scheme := "http"
+	if c.tls() {
+		scheme = "https"
+	}
+	return scheme + "://" + net.JoinHostPort(host, c.Port)
+}
+
+func splitList(v string) []string {
+	var list []string
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+func setBool(field func(*config) *bool) func(*config, string) error {
+	return func(c *config, v string) error {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return err
+		}
+		*field(c) = b
+		return nil
+	}
+}
+
+func setDuration(field func(*config) *duration) func(*config, string) error {
+	return func(c *config, v string) error {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return err
+		}
+		*field(c) = duration(d)
+		return nil
+	}
+}
+
+// duration is a time.Duration written as a string ("30s") in the config
+// file.
+type duration time.Duration
+
+func (d *duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return fmt.Errorf("duration must be a string like \"30s\": %w", err)
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = duration(v)
+	return nil
+}
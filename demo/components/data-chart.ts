@@ -1,18 +1,8 @@
 import { LitElement, html, css, PropertyValues } from 'lit'
 import { customElement, property } from 'lit/decorators.js'
+import type { ChartDataPoint, ChartConfig } from './data-chart.types.js'
 
-export interface ChartDataPoint {
-  name: string
-  value: number
-}
-
-export interface ChartConfig {
-  type: 'bar' | 'line' | 'pie'
-  theme: 'dark' | 'light'
-  showLegend: boolean
-  animate: boolean
-  color: string
-}
+export type { ChartDataPoint, ChartConfig }
 
 /**
  * An ECharts wrapper component
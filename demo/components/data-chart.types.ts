@@ -0,0 +1,14 @@
+// Code generated by gen-signals from internal/signals. DO NOT EDIT.
+
+export interface ChartDataPoint {
+  name: string
+  value: number
+}
+
+export interface ChartConfig {
+  type: 'bar' | 'line' | 'pie'
+  theme: 'dark' | 'light'
+  showLegend: boolean
+  animate: boolean
+  color: string
+}
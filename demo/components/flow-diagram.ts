@@ -1,25 +1,8 @@
 import { LitElement, html, css, PropertyValues } from 'lit'
 import { customElement, property } from 'lit/decorators.js'
+import type { FlowNode, FlowEdge, FlowConfig } from './flow-diagram.types.js'
 
-export interface FlowNode {
-  id: string
-  label: string
-  x: number
-  y: number
-  color?: string
-}
-
-export interface FlowEdge {
-  id: string
-  source: string
-  target: string
-}
-
-export interface FlowConfig {
-  nodeRadius: number
-  lineWidth: number
-  animate: boolean
-}
+export type { FlowNode, FlowEdge, FlowConfig }
 
 /**
  * A simple flow diagram component using Canvas
@@ -0,0 +1,21 @@
+// Code generated by gen-signals from internal/signals. DO NOT EDIT.
+
+export interface FlowNode {
+  id: string
+  label: string
+  x: number
+  y: number
+  color?: string
+}
+
+export interface FlowEdge {
+  id: string
+  source: string
+  target: string
+}
+
+export interface FlowConfig {
+  nodeRadius: number
+  lineWidth: number
+  animate: boolean
+}
@@ -1,13 +1,8 @@
 import { LitElement, html, css, PropertyValues } from 'lit'
 import { customElement, property } from 'lit/decorators.js'
+import type { SceneConfig } from './scene-viewer.types.js'
 
-export interface SceneConfig {
-  rotationSpeed: number
-  color: string
-  wireframe: boolean
-  shape: 'cube' | 'sphere' | 'torus' | 'octahedron'
-  cameraZ: number
-}
+export type { SceneConfig }
 
 /**
  * A Three.js scene viewer component
@@ -0,0 +1,18 @@
+//go:build embed
+
+package main
+
+import (
+	"embed"
+	"io/fs"
+)
+
+// The built site, compiled in with -tags embed after pnpm build so the
+// binary runs without a checkout.
+//
+//go:embed index.html demo/dist states
+var embeddedFiles embed.FS
+
+func embeddedSite() (fs.FS, bool) {
+	return embeddedFiles, true
+}
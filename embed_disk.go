@@ -0,0 +1,11 @@
+//go:build !embed
+
+package main
+
+import "io/fs"
+
+// embeddedSite reports that this binary was built without -tags embed and
+// must serve from disk.
+func embeddedSite() (fs.FS, bool) {
+	return nil, false
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/yacobolo/datastar-lit-examples/internal/fingerprint"
+	"github.com/yacobolo/datastar-lit-examples/internal/signals"
+)
+
+// exportManifest is written to the root of an exported site. Files covers
+// everything in the directory except the manifest itself.
+type exportManifest struct {
+	Assets map[string]string `json:"assets"` // original path -> fingerprinted path
+	Files  map[string]string `json:"files"`  // path -> hex SHA-256
+}
+
+const exportManifestName = "manifest.json"
+
+// export writes a static copy of the site: the rendered page plus the
+// assets it references, fingerprinted. The same sources always produce
+// the same directory, and -verify checks a directory against its manifest.
+func export(args []string) error {
+	fset := flag.NewFlagSet("export", flag.ExitOnError)
+	out := fset.String("o", "_site", "write the site to `dir`, replacing a previous export")
+	root := fset.String("root", ".", "site `dir` containing index.html and demo/dist")
+	state := fset.String("state", "", "boot from the signals in `file` instead of the defaults")
+	verify := fset.Bool("verify", false, "check the site in -o against its manifest instead of exporting")
+	if err := fset.Parse(args); err != nil {
+		return err
+	}
+	if *verify {
+		if err := verifyExport(*out); err != nil {
+			return err
+		}
+		fmt.Printf("%s matches its manifest\n", *out)
+		return nil
+	}
+
+	s := signals.Default()
+	if *state != "" {
+		b, err := os.ReadFile(*state)
+		if err != nil {
+			return err
+		}
+		if s, err = signals.Decode(b); err != nil {
+			return fmt.Errorf("%s: %w", *state, err)
+		}
+	}
+
+	fsys := os.DirFS(*root)
+	page := newPageHandler(fsys)
+	page.static = true
+	var html bytes.Buffer
+	if err := page.render(&html, s); err != nil {
+		return err
+	}
+	site, err := fingerprint.Page(fsys, page.name, html.Bytes())
+	if err != nil {
+		return err
+	}
+
+	files := map[string][]byte{page.name: site.Page}
+	for _, a := range site.Assets {
+		files[a.Name] = a.Data
+	}
+	m := exportManifest{Assets: site.Names(), Files: map[string]string{}}
+	for name, data := range files {
+		sum := sha256.Sum256(data)
+		m.Files[name] = hex.EncodeToString(sum[:])
+	}
+	mb, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	files[exportManifestName] = append(mb, '\n')
+
+	if err := clearExport(*out); err != nil {
+		return err
+	}
+	for _, name := range slices.Sorted(maps.Keys(files)) {
+		file := filepath.Join(*out, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(file, files[name], 0o644); err != nil {
+			return err
+		}
+	}
+	fmt.Printf("Exported %d files to %s\n", len(files), *out)
+	return nil
+}
+
+// clearExport removes a previous export so no stale file survives. It
+// refuses to touch a non-empty directory that has no export manifest.
+func clearExport(dir string) error {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, fs.ErrNotExist) || (err == nil && len(entries) == 0) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(filepath.Join(dir, exportManifestName)); err != nil {
+		return fmt.Errorf("%s is not empty and has no %s; not overwriting it", dir, exportManifestName)
+	}
+	return os.RemoveAll(dir)
+}
+
+// verifyExport checks that dir holds exactly the files its manifest lists,
+// with the listed contents.
+func verifyExport(dir string) error {
+	b, err := os.ReadFile(filepath.Join(dir, exportManifestName))
+	if err != nil {
+		return err
+	}
+	var m exportManifest
+	if err := json.Unmarshal(b, &m); err != nil {
+		return fmt.Errorf("%s: %w", exportManifestName, err)
+	}
+
+	var problems []error
+	seen := map[string]bool{exportManifestName: true}
+	err = filepath.WalkDir(dir, func(file string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(dir, file)
+		if err != nil {
+			return err
+		}
+		name := filepath.ToSlash(rel)
+		if name == exportManifestName {
+			return nil
+		}
+		seen[name] = true
+		want, ok := m.Files[name]
+		if !ok {
+			problems = append(problems, fmt.Errorf("%s: not in manifest", name))
+			return nil
+		}
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		if sum := sha256.Sum256(data); hex.EncodeToString(sum[:]) != want {
+			problems = append(problems, fmt.Errorf("%s: content does not match manifest", name))
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, name := range slices.Sorted(maps.Keys(m.Files)) {
+		if !seen[name] {
+			problems = append(problems, fmt.Errorf("%s: missing", name))
+		}
+	}
+	return errors.Join(problems...)
+}
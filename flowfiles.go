@@ -0,0 +1,201 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/yacobolo/datastar-lit-examples/internal/datastar"
+	"github.com/yacobolo/datastar-lit-examples/internal/dot"
+	"github.com/yacobolo/datastar-lit-examples/internal/flowgraph"
+	"github.com/yacobolo/datastar-lit-examples/internal/mermaid"
+	"github.com/yacobolo/datastar-lit-examples/internal/signals"
+	"github.com/yacobolo/datastar-lit-examples/internal/svg"
+)
+
+// Flow diagrams as files in other tools' formats.
+
+// flowFormat is a text format a diagram can be downloaded in.
+type flowFormat struct {
+	ext         string
+	contentType string
+	encode      func(io.Writer, signals.Flow) error
+}
+
+// flowFormats is keyed by the {format} of the export routes.
+var flowFormats = map[string]flowFormat{
+	"dot":     {".dot", "text/vnd.graphviz; charset=utf-8", dot.Encode},
+	"mermaid": {".mmd", "text/vnd.mermaid; charset=utf-8", mermaid.Encode},
+	"svg":     {".svg", "image/svg+xml", svg.Encode},
+}
+
+func registerFlowFiles(mux *http.ServeMux) {
+	mux.HandleFunc("GET /api/flow/export/{format}", handleFlowExport)
+	mux.HandleFunc("POST /api/flow/import", handleFlowImport)
+	mux.HandleFunc("GET /api/flow/svg", handleFlowSVG)
+}
+
+// handleFlowExport downloads the $flow sent in ?datastar=.
+func handleFlowExport(w http.ResponseWriter, r *http.Request) {
+	flow, err := signals.ReadFlow(r)
+	if err != nil {
+		badSignals(w, err)
+		return
+	}
+	writeFlowFile(w, r, "flow", flow)
+}
+
+// writeFlowFile sends flow as an attachment in the {format} of r, named
+// after name.
+func writeFlowFile(w http.ResponseWriter, r *http.Request, name string, flow signals.Flow) {
+	format, ok := flowFormats[r.PathValue("format")]
+	if !ok {
+		http.Error(w, "format must be dot, mermaid or svg", http.StatusNotFound)
+		return
+	}
+	var buf bytes.Buffer
+	if err := format.encode(&buf, flow); err != nil {
+		slog.ErrorContext(r.Context(), "encode flow failed", "err", err)
+		http.Error(w, "encode failed", http.StatusInternalServerError)
+		return
+	}
+	h := w.Header()
+	h.Set("Content-Type", format.contentType)
+	h.Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{
+		"filename": fileName(name) + format.ext,
+	}))
+	h.Set("Cache-Control", "no-store")
+	w.Write(buf.Bytes())
+}
+
+// handleFlowSVG draws the $flow sent in ?datastar=, for an <img> or a
+// print view.
+func handleFlowSVG(w http.ResponseWriter, r *http.Request) {
+	flow, err := signals.ReadFlow(r)
+	if err != nil {
+		badSignals(w, err)
+		return
+	}
+	writeSVG(w, r, flow)
+}
+
+// writeSVG sends flow as an SVG image to show in place.
+func writeSVG(w http.ResponseWriter, r *http.Request, flow signals.Flow) {
+	var buf bytes.Buffer
+	if err := svg.Encode(&buf, flow); err != nil {
+		slog.ErrorContext(r.Context(), "encode flow failed", "err", err)
+		http.Error(w, "encode failed", http.StatusInternalServerError)
+		return
+	}
+	h := w.Header()
+	h.Set("Content-Type", "image/svg+xml")
+	h.Set("Cache-Control", "no-store")
+	w.Write(buf.Bytes())
+}
+
+var fileNameUnsafe = regexp.MustCompile(`[^a-z0-9]+`)
+
+// fileName turns a document name into a plain file name.
+func fileName(name string) string {
+	name = strings.Trim(fileNameUnsafe.ReplaceAllString(strings.ToLower(name), "-"), "-")
+	if name == "" {
+		return "flow"
+	}
+	return name
+}
+
+// maxImport caps the size of an uploaded diagram.
+const maxImport = 1 << 20
+
+var dotHeader = regexp.MustCompile(`(?im)^\s*(strict\s+)?(di)?graph\b[^{\n]*\{`)
+
+// handleFlowImport replaces the diagram with one read from DOT or Mermaid.
+// The form posts the text as a file upload or pasted into a textarea,
+// along with the current node radius for the layout. The format follows
+// from the file extension, or else from the text. The outcome, a parse
+// error with its line and column included, is shown in
+// #flow-import-status. Diagrams past flowgraph.MaxNodes or
+// flowgraph.MaxEdges are refused like parse errors.
+func handleFlowImport(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxImport)
+	if err := r.ParseMultipartForm(maxImport); err != nil && !errors.Is(err, http.ErrNotMultipart) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	src, name := []byte(r.FormValue("text")), ""
+	if f, hdr, err := r.FormFile("file"); err == nil {
+		defer f.Close()
+		if src, err = io.ReadAll(f); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		name = hdr.Filename
+	}
+
+	opts := flowgraph.DefaultOptions
+	if v, err := strconv.ParseFloat(r.FormValue("nodeRadius"), 64); err == nil && v > 0 {
+		opts.NodeRadius = v
+	}
+	var (
+		flow   signals.Flow
+		err    error
+		format = "Mermaid"
+	)
+	switch ext := strings.ToLower(path.Ext(name)); {
+	case ext == ".dot" || ext == ".gv" || ext != ".mmd" && ext != ".mermaid" && dotHeader.Match(src):
+		format = "DOT"
+		flow, err = dot.Parse(src, opts)
+	default:
+		flow, err = mermaid.Parse(src, opts)
+	}
+	if err == nil {
+		// The parsers stop at the same limits; this keeps the handler
+		// safe whatever reads the source.
+		err = flowgraph.CheckSize(flow)
+	}
+
+	sse := datastar.NewSSE(w, r)
+	status := func(class, msg string) {
+		html := fmt.Sprintf(`<p id="flow-import-status" class="%s">%s</p>`, class, template.HTMLEscapeString(msg))
+		if err := sse.PatchElements(html); err != nil {
+			slog.WarnContext(sse.Context(), "patch elements failed", "err", err)
+		}
+	}
+	if len(bytes.TrimSpace(src)) == 0 {
+		status("flow-issues has-errors", "Choose a file or paste a diagram first")
+		return
+	}
+	if err != nil {
+		msg := err.Error()
+		if name != "" {
+			msg = path.Base(name) + ":" + msg
+		}
+		status("flow-issues has-errors", msg)
+		return
+	}
+
+	docName := "Imported"
+	if name != "" {
+		docName = strings.TrimSuffix(path.Base(name), path.Ext(name))
+	}
+	ok := patch(sse, map[string]any{
+		"flow": map[string]any{
+			"nodes":  flow.Nodes,
+			"edges":  flow.Edges,
+			"errors": flowgraph.Check(flow),
+		},
+		"doc": signals.Doc{Name: docName},
+	})
+	if ok {
+		status("doc-meta", fmt.Sprintf("Imported %d nodes and %d edges from %s", len(flow.Nodes), len(flow.Edges), format))
+	}
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"html/template"
+	"log/slog"
+	"net/http"
+
+	"github.com/yacobolo/datastar-lit-examples/internal/datastar"
+	"github.com/yacobolo/datastar-lit-examples/internal/flowstore"
+	"github.com/yacobolo/datastar-lit-examples/internal/signals"
+)
+
+// Saved flow diagrams. Saving reads $flow and $doc from the action, loading
+// patches them back, and every change re-renders the #flow-docs list.
+
+type flowDocs struct {
+	store *flowstore.Store
+}
+
+func (d flowDocs) register(mux *http.ServeMux) {
+	mux.HandleFunc("GET /api/flows", d.list)
+	mux.HandleFunc("POST /api/flows", d.create)
+	mux.HandleFunc("GET /api/flows/{id}", d.load)
+	mux.HandleFunc("PUT /api/flows/{id}", d.save)
+	mux.HandleFunc("DELETE /api/flows/{id}", d.delete)
+	mux.HandleFunc("GET /api/flows/{id}/export/{format}", d.export)
+	mux.HandleFunc("GET /api/flows/{id}/svg", d.svg)
+}
+
+// list renders the saved documents into #flow-docs.
+func (d flowDocs) list(w http.ResponseWriter, r *http.Request) {
+	sse := datastar.NewSSE(w, r)
+	d.patchList(sse)
+}
+
+// create saves the current diagram as a new document and makes it the one
+// the page is editing.
+func (d flowDocs) create(w http.ResponseWriter, r *http.Request) {
+	flow, doc, err := signals.ReadFlowDoc(r)
+	if err != nil {
+		badSignals(w, err)
+		return
+	}
+	saved, err := d.store.Create(doc.Name, flow)
+	if err != nil {
+		flowStoreError(w, r, err)
+		return
+	}
+	sse := datastar.NewSSE(w, r)
+	if patch(sse, map[string]any{"doc": signals.Doc{ID: saved.ID, Name: saved.Name}}) {
+		d.patchList(sse)
+	}
+}
+
+// load replaces $flow with a saved document.
+func (d flowDocs) load(w http.ResponseWriter, r *http.Request) {
+	saved, err := d.store.Get(r.PathValue("id"))
+	if err != nil {
+		flowStoreError(w, r, err)
+		return
+	}
+	sse := datastar.NewSSE(w, r)
+	patch(sse, map[string]any{
+		"flow": flowPatch(saved.Flow),
+		"doc":  signals.Doc{ID: saved.ID, Name: saved.Name},
+	})
+}
+
+// save overwrites a document with the current diagram and name.
+func (d flowDocs) save(w http.ResponseWriter, r *http.Request) {
+	flow, doc, err := signals.ReadFlowDoc(r)
+	if err != nil {
+		badSignals(w, err)
+		return
+	}
+	saved, err := d.store.Save(r.PathValue("id"), doc.Name, flow)
+	if err != nil {
+		flowStoreError(w, r, err)
+		return
+	}
+	sse := datastar.NewSSE(w, r)
+	if patch(sse, map[string]any{"doc": signals.Doc{ID: saved.ID, Name: saved.Name}}) {
+		d.patchList(sse)
+	}
+}
+
+// delete removes a document. If the page was editing it, the diagram stays
+// on the canvas but is no longer tied to a document.
+func (d flowDocs) delete(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if err := d.store.Delete(id); err != nil {
+		flowStoreError(w, r, err)
+		return
+	}
+	sse := datastar.NewSSE(w, r)
+	if doc, err := signals.ReadDoc(r); err == nil && doc.ID == id {
+		if !patch(sse, map[string]any{"doc": signals.Doc{}}) {
+			return
+		}
+	}
+	d.patchList(sse)
+}
+
+// export downloads a saved document, named after it.
+func (d flowDocs) export(w http.ResponseWriter, r *http.Request) {
+	saved, err := d.store.Get(r.PathValue("id"))
+	if err != nil {
+		flowStoreError(w, r, err)
+		return
+	}
+	writeFlowFile(w, r, saved.Name, saved.Flow)
+}
+
+// svg draws a saved document, so it can be linked as an image.
+func (d flowDocs) svg(w http.ResponseWriter, r *http.Request) {
+	saved, err := d.store.Get(r.PathValue("id"))
+	if err != nil {
+		flowStoreError(w, r, err)
+		return
+	}
+	writeSVG(w, r, saved.Flow)
+}
+
+var flowDocsList = template.Must(template.New("flow-docs").Parse(`<ul id="flow-docs" class="doc-list">
+{{- range .}}
+<li><button class="btn-secondary" data-on:click="@get('/api/flows/{{.ID}}')">{{.Name}}</button> <span class="doc-meta">{{.Nodes}} nodes &middot; {{.Updated.Format "Jan 2 15:04"}}</span> <button class="btn-ghost btn-sm" title="Delete" data-on:click="@delete('/api/flows/{{.ID}}')">&times;</button></li>
+{{- else}}
+<li class="doc-meta">No saved diagrams yet</li>
+{{- end}}
+</ul>`))
+
+func (d flowDocs) patchList(sse *datastar.SSE) {
+	infos, err := d.store.List()
+	if err != nil {
+		slog.ErrorContext(sse.Context(), "list flows failed", "err", err)
+		return
+	}
+	var buf bytes.Buffer
+	if err := flowDocsList.Execute(&buf, infos); err != nil {
+		slog.ErrorContext(sse.Context(), "render flow list failed", "err", err)
+		return
+	}
+	if err := sse.PatchElements(buf.String()); err != nil {
+		slog.WarnContext(sse.Context(), "patch elements failed", "err", err)
+	}
+}
+
+// flowStoreError answers a failed store call before any SSE is sent.
+func flowStoreError(w http.ResponseWriter, r *http.Request, err error) {
+	var invalid signals.ValidationError
+	switch {
+	case errors.Is(err, flowstore.ErrNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.As(err, &invalid):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		slog.ErrorContext(r.Context(), "flow store failed", "err", err)
+		http.Error(w, "flow store failed", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,7 @@
+module github.com/yacobolo/datastar-lit-examples
+
+go 1.24
+
+require github.com/evanw/esbuild v0.24.2
+
+require golang.org/x/sys v0.0.0-20220715151400-c0bba94af5f8 // indirect
@@ -0,0 +1,274 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/yacobolo/datastar-lit-examples/internal/datastar"
+	"github.com/yacobolo/datastar-lit-examples/internal/flowgraph"
+	"github.com/yacobolo/datastar-lit-examples/internal/signals"
+)
+
+// Server-driven actions. Each handler reads the signals Datastar posts with
+// the action and patches the result back over SSE; the Lit components pick
+// the change up through data-attr exactly like a client-side mutation.
+
+func registerHandlers(mux *http.ServeMux) {
+	mux.HandleFunc("POST /api/flow/nodes", handleFlowAddNode)
+	mux.HandleFunc("POST /api/flow/check", handleFlowCheck)
+	mux.HandleFunc("POST /api/flow/layout", handleFlowLayout)
+	mux.HandleFunc("POST /api/scene/shape", handleSceneNextShape)
+	mux.HandleFunc("POST /api/chart/randomize", handleChartRandomize)
+	mux.HandleFunc("GET /api/chart/live", handleChartLive)
+}
+
+// handleFlowAddNode appends a node wired to the last one. IDs stay numeric
+// because the component derives each edge's animation phase from them.
+func handleFlowAddNode(w http.ResponseWriter, r *http.Request) {
+	flow, err := signals.ReadFlow(r)
+	if err != nil {
+		badSignals(w, err)
+		return
+	}
+	nodes, edges := flow.Nodes, flow.Edges
+
+	node := signals.FlowNode{
+		ID:    strconv.Itoa(nextID(len(nodes), func(i int) string { return nodes[i].ID })),
+		Label: "Server",
+		X:     float64(50 + rand.IntN(300)),
+		Y:     float64(50 + rand.IntN(200)),
+		Color: "#f59e0b",
+	}
+	if len(nodes) > 0 {
+		edges = append(edges, signals.FlowEdge{
+			ID:     strconv.Itoa(nextID(len(edges), func(i int) string { return edges[i].ID })),
+			Source: nodes[len(nodes)-1].ID,
+			Target: node.ID,
+		})
+	}
+	flow.Nodes, flow.Edges = append(nodes, node), edges
+
+	sse := datastar.NewSSE(w, r)
+	patch(sse, map[string]any{"flow": flowPatch(flow)})
+}
+
+// handleFlowCheck reports the graph's issues as $flow.errors. The page calls
+// it whenever it changes the nodes or edges itself.
+func handleFlowCheck(w http.ResponseWriter, r *http.Request) {
+	flow, err := signals.ReadFlow(r)
+	if err != nil {
+		badSignals(w, err)
+		return
+	}
+	if err := flowgraph.CheckSize(flow); err != nil {
+		http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+		return
+	}
+	sse := datastar.NewSSE(w, r)
+	patch(sse, map[string]any{"flow": map[string]any{"errors": flowgraph.Check(flow)}})
+}
+
+// layoutFrame is the delay between streamed layout frames, about 30 a
+// second.
+const layoutFrame = 33 * time.Millisecond
+
+// handleFlowLayout repositions the nodes with the engine named by
+// ?algorithm= (layered, force or grid) on a canvas of ?width= by ?height=.
+// With ?animate=true it streams the nodes moving into place: the force
+// simulation's own iterations, or an eased transition for the others.
+func handleFlowLayout(w http.ResponseWriter, r *http.Request) {
+	flow, err := signals.ReadFlow(r)
+	if err != nil {
+		badSignals(w, err)
+		return
+	}
+	if err := flowgraph.CheckSize(flow); err != nil {
+		http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+		return
+	}
+	q := r.URL.Query()
+	algorithm := flowgraph.Algorithm(q.Get("algorithm"))
+	if algorithm == "" {
+		algorithm = flowgraph.Layered
+	}
+	if !slices.Contains(flowgraph.Algorithms, algorithm) {
+		http.Error(w, fmt.Sprintf("algorithm must be one of %v, got %q", flowgraph.Algorithms, algorithm), http.StatusBadRequest)
+		return
+	}
+	opts := flowgraph.DefaultOptions
+	opts.NodeRadius = flow.Config.NodeRadius
+	for name, v := range map[string]*float64{"width": &opts.Width, "height": &opts.Height} {
+		if s := q.Get(name); s != "" {
+			f, err := strconv.ParseFloat(s, 64)
+			if err != nil || f < 100 || f > 10000 {
+				http.Error(w, fmt.Sprintf("%s must be a number from 100 to 10000, got %q", name, s), http.StatusBadRequest)
+				return
+			}
+			*v = f
+		}
+	}
+	animate, _ := strconv.ParseBool(q.Get("animate"))
+
+	sse := datastar.NewSSE(w, r)
+	var frames [][]signals.FlowNode
+	var step func([]signals.FlowNode) bool
+	if animate && algorithm == flowgraph.Force {
+		step = func(nodes []signals.FlowNode) bool {
+			frames = append(frames, nodes)
+			return true
+		}
+	}
+	nodes := flowgraph.Layout(algorithm, flow, opts, step)
+	if animate && step == nil {
+		const n = 20
+		for i := 1; i < n; i++ {
+			frames = append(frames, flowgraph.Tween(flow.Nodes, nodes, float64(i)/n))
+		}
+	}
+
+	ticker := time.NewTicker(layoutFrame)
+	defer ticker.Stop()
+	for i, frame := range frames {
+		// The force simulation moves in small steps; every other one is
+		// smooth enough.
+		if step != nil && i%2 == 1 {
+			continue
+		}
+		if !patch(sse, map[string]any{"flow": map[string]any{"nodes": frame}}) {
+			return
+		}
+		select {
+		case <-sse.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+	flow.Nodes = nodes
+	patch(sse, map[string]any{"flow": flowPatch(flow)})
+}
+
+// handleSceneNextShape advances the scene to the next shape in the cycle.
+func handleSceneNextShape(w http.ResponseWriter, r *http.Request) {
+	scene, err := signals.ReadScene(r)
+	if err != nil {
+		badSignals(w, err)
+		return
+	}
+
+	i := slices.Index(signals.Shapes, scene.Config.Shape)
+	next := signals.Shapes[(i+1)%len(signals.Shapes)]
+
+	sse := datastar.NewSSE(w, r)
+	patch(sse, map[string]any{"scene": map[string]any{"config": map[string]any{"shape": next}}})
+}
+
+// handleChartRandomize keeps the current labels and rolls new values.
+func handleChartRandomize(w http.ResponseWriter, r *http.Request) {
+	chart, err := signals.ReadChart(r)
+	if err != nil {
+		badSignals(w, err)
+		return
+	}
+
+	data := chart.Data
+	for i := range data {
+		data[i].Value = randomValue()
+	}
+
+	sse := datastar.NewSSE(w, r)
+	patch(sse, map[string]any{"chart": map[string]any{"data": data}})
+}
+
+// handleChartLive streams a rolling window of data points, one per second,
+// until the client disconnects.
+func handleChartLive(w http.ResponseWriter, r *http.Request) {
+	chart, err := signals.ReadChart(r)
+	if err != nil {
+		badSignals(w, err)
+		return
+	}
+	data := chart.Data
+	window := max(len(data), 7)
+
+	sse := datastar.NewSSE(w, r)
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for tick := 1; ; tick++ {
+		select {
+		case <-sse.Done():
+			return
+		case now := <-ticker.C:
+			data = append(data, signals.ChartDataPoint{Name: now.Format("15:04:05"), Value: randomValue()})
+			if len(data) > window {
+				data = data[len(data)-window:]
+			}
+			if !patch(sse, map[string]any{"chart": map[string]any{"data": data}}) {
+				return
+			}
+			status := fmt.Sprintf(`<span id="chart-live" class="value-display">live &middot; %d</span>`, tick)
+			if err := sse.PatchElements(status); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// badSignals answers a request whose signals could not be read: 413 if
+// the body was over datastar.MaxBodySize, 400 otherwise.
+func badSignals(w http.ResponseWriter, err error) {
+	status := http.StatusBadRequest
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		status = http.StatusRequestEntityTooLarge
+	}
+	http.Error(w, err.Error(), status)
+}
+
+// patch sends a signal patch and reports whether the stream is still usable.
+func patch(sse *datastar.SSE, v any) bool {
+	if err := sse.PatchSignals(v); err != nil {
+		slog.WarnContext(sse.Context(), "patch signals failed", "err", err)
+		return false
+	}
+	return true
+}
+
+// flowPatch is the whole flow signal with its issues checked, for handlers
+// that change the graph. Missing lists are sent empty, because null would
+// delete the signal.
+func flowPatch(f signals.Flow) map[string]any {
+	if f.Nodes == nil {
+		f.Nodes = []signals.FlowNode{}
+	}
+	if f.Edges == nil {
+		f.Edges = []signals.FlowEdge{}
+	}
+	return map[string]any{
+		"nodes":  f.Nodes,
+		"edges":  f.Edges,
+		"config": f.Config,
+		"errors": flowgraph.Check(f),
+	}
+}
+
+// nextID returns one past the largest numeric ID among n items.
+func nextID(n int, id func(int) string) int {
+	next := 1
+	for i := range n {
+		if v, err := strconv.Atoi(id(i)); err == nil && v >= next {
+			next = v + 1
+		}
+	}
+	return next
+}
+
+func randomValue() float64 {
+	return float64(rand.IntN(200) + 50)
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"runtime/debug"
+	"strings"
+
+	"github.com/yacobolo/datastar-lit-examples/internal/fingerprint"
+)
+
+// componentsBundle is the asset /version reports the hash of.
+const componentsBundle = "demo/dist/components.js"
+
+// readyAssets must exist before the page can work.
+var readyAssets = []string{componentsBundle, "demo/dist/styles.css"}
+
+// probes serves the endpoints orchestrators and operators poll: liveness,
+// readiness and build information.
+type probes struct {
+	site     fs.FS
+	versions *fingerprint.Table // nil in dev mode
+	checks   []readyCheck
+}
+
+// readyCheck is one condition of readiness; check returns nil when met.
+type readyCheck struct {
+	name  string
+	check func() error
+}
+
+func newProbes(site, states fs.FS, versions *fingerprint.Table) *probes {
+	p := &probes{site: site, versions: versions}
+	p.addCheck("assets", func() error {
+		for _, name := range readyAssets {
+			if _, err := fs.Stat(site, name); errors.Is(err, fs.ErrNotExist) {
+				return fmt.Errorf("%s has not been built", name)
+			} else if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	p.addCheck("states", func() error {
+		// No saved states is fine; a directory that cannot be read is not.
+		if _, err := fs.ReadDir(states, "."); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		return nil
+	})
+	return p
+}
+
+// addCheck makes readiness depend on check as well.
+func (p *probes) addCheck(name string, check func() error) {
+	p.checks = append(p.checks, readyCheck{name, check})
+}
+
+func (p *probes) register(mux *http.ServeMux) {
+	mux.HandleFunc("GET /healthz", p.healthz)
+	mux.HandleFunc("GET /readyz", p.readyz)
+	mux.HandleFunc("GET /version", p.version)
+}
+
+// healthz answers as long as the process can serve requests at all.
+func (p *probes) healthz(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "no-store")
+	w.Write([]byte("ok\n"))
+}
+
+// readyz runs every check and reports each result, with 503 if any failed.
+func (p *probes) readyz(w http.ResponseWriter, r *http.Request) {
+	status, code := "ready", http.StatusOK
+	checks := map[string]string{}
+	for _, c := range p.checks {
+		if err := c.check(); err != nil {
+			checks[c.name] = err.Error()
+			status, code = "not ready", http.StatusServiceUnavailable
+		} else {
+			checks[c.name] = "ok"
+		}
+	}
+	writeProbeJSON(w, code, map[string]any{"status": status, "checks": checks})
+}
+
+type buildVersion struct {
+	Go         string            `json:"go"`
+	Path       string            `json:"path"`
+	Version    string            `json:"version"`
+	VCS        map[string]string `json:"vcs,omitempty"`
+	Deps       []moduleVersion   `json:"deps"`
+	Components *bundleVersion    `json:"components"`
+}
+
+type moduleVersion struct {
+	Path    string `json:"path"`
+	Version string `json:"version"`
+	Sum     string `json:"sum,omitempty"`
+}
+
+type bundleVersion struct {
+	File   string `json:"file"`
+	SHA256 string `json:"sha256"`
+}
+
+// version reports the binary's module and VCS stamp, and the hash of the
+// component bundle as it is currently served.
+func (p *probes) version(w http.ResponseWriter, r *http.Request) {
+	var v buildVersion
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		v.Go, v.Path, v.Version = bi.GoVersion, bi.Main.Path, bi.Main.Version
+		for _, s := range bi.Settings {
+			// vcs, vcs.revision, vcs.time and vcs.modified
+			if s.Key == "vcs" {
+				s.Key = "vcs.system"
+			}
+			if name, ok := strings.CutPrefix(s.Key, "vcs."); ok {
+				if v.VCS == nil {
+					v.VCS = map[string]string{}
+				}
+				v.VCS[name] = s.Value
+			}
+		}
+		for _, d := range bi.Deps {
+			if d.Replace != nil {
+				d = d.Replace
+			}
+			v.Deps = append(v.Deps, moduleVersion{d.Path, d.Version, d.Sum})
+		}
+	}
+	v.Components = p.components()
+	writeProbeJSON(w, http.StatusOK, v)
+}
+
+// components hashes the bundle the page loads: the fingerprinted copy when
+// assets are fingerprinted, the file itself otherwise.
+func (p *probes) components() *bundleVersion {
+	if p.versions != nil {
+		if a, ok := p.versions.Current(componentsBundle); ok {
+			return &bundleVersion{File: a.Name, SHA256: a.Sum}
+		}
+	}
+	b, err := fs.ReadFile(p.site, componentsBundle)
+	if err != nil {
+		return nil
+	}
+	sum := sha256.Sum256(b)
+	return &bundleVersion{File: componentsBundle, SHA256: hex.EncodeToString(sum[:])}
+}
+
+func writeProbeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(code)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(v)
+}
@@ -0,0 +1,102 @@
+// Package accesslog writes one structured log record per request and gives
+// each request the ID the rest of the server logs it under.
+package accesslog
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/yacobolo/datastar-lit-examples/internal/requestid"
+)
+
+// Entry is the outcome of one request.
+type Entry struct {
+	Request  *http.Request
+	Status   int
+	Bytes    int64 // body bytes written
+	Duration time.Duration
+	Aborted  bool // the handler panicked, e.g. an SSE stream cut off on shutdown
+}
+
+// Handler logs every request to next once it completes, including SSE
+// streams that end by being aborted, and passes the same entry to each
+// observer. The request ID is set on the context and returned in the
+// X-Request-Id header.
+func Handler(logger *slog.Logger, next http.Handler, observers ...func(Entry)) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := requestid.New()
+		r = r.WithContext(requestid.With(r.Context(), id))
+		w.Header().Set(requestid.Header, id)
+
+		rw := &responseWriter{ResponseWriter: w}
+		start := time.Now()
+		aborted := true
+		defer func() {
+			e := Entry{Request: r, Status: rw.status, Bytes: rw.bytes, Duration: time.Since(start), Aborted: aborted}
+			for _, observe := range observers {
+				observe(e)
+			}
+			attrs := []slog.Attr{
+				slog.String("method", r.Method),
+				slog.String("path", r.URL.Path),
+				slog.Int("status", e.Status),
+				slog.Int64("bytes", e.Bytes),
+				slog.Duration("duration", e.Duration),
+				slog.String("remote", r.RemoteAddr),
+			}
+			if last := r.Header.Get("Last-Event-Id"); last != "" {
+				attrs = append(attrs, slog.String("last_event_id", last))
+			}
+			if e.Aborted {
+				attrs = append(attrs, slog.Bool("aborted", true))
+			}
+			level := slog.LevelInfo
+			if e.Status >= 500 {
+				level = slog.LevelError
+			}
+			logger.LogAttrs(r.Context(), level, "request", attrs...)
+		}()
+		next.ServeHTTP(rw, r)
+		if rw.status == 0 {
+			rw.status = http.StatusOK
+		}
+		aborted = false
+	})
+}
+
+// responseWriter records the status and the number of body bytes written.
+type responseWriter struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+}
+
+func (w *responseWriter) WriteHeader(code int) {
+	if w.status == 0 && code >= 200 {
+		w.status = code
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *responseWriter) Write(p []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(p)
+	w.bytes += int64(n)
+	return n, err
+}
+
+func (w *responseWriter) FlushError() error {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+func (w *responseWriter) Flush() { _ = w.FlushError() }
+
+// Unwrap gives http.ResponseController access to the connection for
+// deadlines.
+func (w *responseWriter) Unwrap() http.ResponseWriter { return w.ResponseWriter }
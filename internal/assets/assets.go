@@ -0,0 +1,110 @@
+// Package assets builds the demo's JavaScript and CSS bundles with esbuild's
+// Go API: demo/components/index.ts to demo/dist/components.js and
+// demo/styles/main.css to demo/dist/styles.css.
+package assets
+
+import (
+	"cmp"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/evanw/esbuild/pkg/api"
+)
+
+// Externals are resolved by the importmap in index.html, not bundled.
+var Externals = []string{"lit", "lit/decorators.js", "lit/directives/repeat.js", "three", "echarts"}
+
+// Options configure both builds.
+type Options struct {
+	// Dir is the repository root the entry points are relative to.
+	Dir string
+	// Minify is what NODE_ENV=production turned on in esbuild.config.js.
+	Minify bool
+}
+
+// Bundle is one of the builds.
+type Bundle struct {
+	Name    string
+	Options api.BuildOptions
+}
+
+// Bundles returns the component and stylesheet builds.
+func Bundles(o Options) ([]Bundle, error) {
+	dir, err := filepath.Abs(cmp.Or(o.Dir, "."))
+	if err != nil {
+		return nil, err
+	}
+	return []Bundle{
+		{
+			Name: "components",
+			Options: api.BuildOptions{
+				AbsWorkingDir:     dir,
+				EntryPoints:       []string{"demo/components/index.ts"},
+				Bundle:            true,
+				Format:            api.FormatESModule,
+				Outfile:           "demo/dist/components.js",
+				MinifyWhitespace:  o.Minify,
+				MinifyIdentifiers: o.Minify,
+				MinifySyntax:      o.Minify,
+				Sourcemap:         api.SourceMapLinked,
+				Target:            api.ES2021,
+				External:          Externals,
+				Write:             true,
+				LogLevel:          api.LogLevelSilent,
+			},
+		},
+		{
+			Name: "styles",
+			Options: api.BuildOptions{
+				AbsWorkingDir:     dir,
+				EntryPoints:       []string{"demo/styles/main.css"},
+				Bundle:            true,
+				Outfile:           "demo/dist/styles.css",
+				MinifyWhitespace:  o.Minify,
+				MinifyIdentifiers: o.Minify,
+				MinifySyntax:      o.Minify,
+				Sourcemap:         api.SourceMapLinked,
+				// Modern browsers that support CSS nesting, @layer, etc.
+				Engines: []api.Engine{
+					{Name: api.EngineChrome, Version: "120"},
+					{Name: api.EngineFirefox, Version: "120"},
+					{Name: api.EngineSafari, Version: "17"},
+				},
+				Write:    true,
+				LogLevel: api.LogLevelSilent,
+			},
+		},
+	}, nil
+}
+
+// Build runs every bundle in order and stops at the first that fails.
+func Build(o Options) error {
+	bundles, err := Bundles(o)
+	if err != nil {
+		return err
+	}
+	for _, b := range bundles {
+		result := api.Build(b.Options)
+		if len(result.Errors) > 0 {
+			return &BuildError{Bundle: b.Name, Messages: result.Errors}
+		}
+	}
+	return nil
+}
+
+// BuildError carries esbuild's error messages for one bundle.
+type BuildError struct {
+	Bundle   string
+	Messages []api.Message
+}
+
+func (e *BuildError) Error() string {
+	return fmt.Sprintf("%s build failed:\n%s", e.Bundle, strings.Join(Format(e.Messages), ""))
+}
+
+// Format renders esbuild messages the way the esbuild CLI prints them,
+// without terminal colors.
+func Format(msgs []api.Message) []string {
+	return api.FormatMessages(msgs, api.FormatMessagesOptions{Kind: api.ErrorMessage})
+}
@@ -0,0 +1,212 @@
+// Package compress negotiates response compression. Handler compresses
+// text responses on the fly with zstd or gzip, flushing the compressor
+// whenever the handler flushes so SSE events are not held back; Negotiate
+// picks between precompressed variants of static files.
+package compress
+
+import (
+	"compress/gzip"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/yacobolo/datastar-lit-examples/internal/zstd"
+)
+
+// Content codings, by Content-Encoding token.
+const (
+	Gzip = "gzip"
+	Zstd = "zstd"
+)
+
+// minSize is the smallest response with a known length worth compressing.
+const minSize = 512
+
+// Negotiate returns the coding of offered, in order of preference, that
+// the request's Accept-Encoding rates highest, or "" for none.
+func Negotiate(r *http.Request, offered ...string) string {
+	header := r.Header.Values("Accept-Encoding")
+	if len(header) == 0 {
+		return ""
+	}
+	q := map[string]float64{}
+	for _, part := range strings.Split(strings.Join(header, ","), ",") {
+		coding, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		coding = strings.ToLower(strings.TrimSpace(coding))
+		if coding == "" {
+			continue
+		}
+		weight := 1.0
+		if k, v, ok := strings.Cut(strings.TrimSpace(params), "="); ok && strings.TrimSpace(k) == "q" {
+			if f, err := strconv.ParseFloat(strings.TrimSpace(v), 64); err == nil {
+				weight = f
+			}
+		}
+		q[coding] = weight
+	}
+
+	best, bestQ := "", 0.0
+	for _, coding := range offered {
+		w, ok := q[coding]
+		if !ok {
+			w = q["*"]
+		}
+		if w > bestQ {
+			best, bestQ = coding, w
+		}
+	}
+	return best
+}
+
+// Compressible reports whether a response of this Content-Type benefits
+// from compression.
+func Compressible(contentType string) bool {
+	t, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	switch {
+	case strings.HasPrefix(t, "text/"):
+		return true
+	case strings.HasSuffix(t, "+json"), strings.HasSuffix(t, "+xml"):
+		return true
+	}
+	switch t {
+	case "application/javascript", "application/json", "application/xml", "image/svg+xml":
+		return true
+	}
+	return false
+}
+
+// AddVary adds Accept-Encoding to the Vary header once.
+func AddVary(h http.Header) {
+	for _, v := range h.Values("Vary") {
+		for _, f := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(f), "Accept-Encoding") {
+				return
+			}
+		}
+	}
+	h.Add("Vary", "Accept-Encoding")
+}
+
+// An encoder is a compressor that can be flushed mid-stream and reused.
+type encoder interface {
+	io.WriteCloser
+	Flush() error
+	Reset(io.Writer)
+}
+
+// encoders pools the compressors of each coding Handler offers.
+var encoders = map[string]*sync.Pool{
+	Zstd: {New: func() any { return zstd.NewWriter(nil) }},
+	Gzip: {New: func() any { return gzip.NewWriter(nil) }},
+}
+
+// Handler compresses the responses of next for clients that accept zstd
+// or gzip, preferring zstd as static does. Only compressible types are
+// touched, and responses that already carry a
+// Content-Encoding, such as precompressed files, pass through. The ETag of
+// a compressed response is made weak: the bytes differ from the identity
+// response, but a weak If-None-Match still matches the handler's tag.
+func Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		coding := Negotiate(r, Zstd, Gzip)
+		if r.Method == http.MethodHead || r.Header.Get("Range") != "" || coding == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		cw := &responseWriter{ResponseWriter: w, coding: coding}
+		next.ServeHTTP(cw, r)
+		cw.close()
+	})
+}
+
+type responseWriter struct {
+	http.ResponseWriter
+	coding      string
+	wroteHeader bool
+	enc         encoder // set once the response is being compressed
+}
+
+func (w *responseWriter) WriteHeader(code int) {
+	if w.wroteHeader || code < 200 {
+		w.ResponseWriter.WriteHeader(code)
+		return
+	}
+	w.wroteHeader = true
+
+	h := w.Header()
+	if h.Get("Content-Encoding") == "" {
+		switch {
+		case code == http.StatusNotModified:
+			// Keep the tag consistent with the compressed 200 it confirms.
+			weaken(h)
+		case code != http.StatusNoContent && code != http.StatusPartialContent &&
+			Compressible(h.Get("Content-Type")) && !small(h):
+			h.Del("Content-Length")
+			h.Del("Accept-Ranges")
+			h.Set("Content-Encoding", w.coding)
+			AddVary(h)
+			weaken(h)
+			w.enc = encoders[w.coding].Get().(encoder)
+			w.enc.Reset(w.ResponseWriter)
+		}
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *responseWriter) Write(p []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.enc != nil {
+		return w.enc.Write(p)
+	}
+	return w.ResponseWriter.Write(p)
+}
+
+// FlushError pushes everything compressed so far to the client. It is
+// what http.ResponseController.Flush calls, so every SSE event reaches the
+// browser as soon as it is sent.
+func (w *responseWriter) FlushError() error {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.enc != nil {
+		if err := w.enc.Flush(); err != nil {
+			return err
+		}
+	}
+	return http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+func (w *responseWriter) Flush() { _ = w.FlushError() }
+
+// Unwrap gives http.ResponseController access to the connection for
+// deadlines.
+func (w *responseWriter) Unwrap() http.ResponseWriter { return w.ResponseWriter }
+
+func (w *responseWriter) close() {
+	if w.enc == nil {
+		return
+	}
+	_ = w.enc.Close()
+	w.enc.Reset(nil)
+	encoders[w.coding].Put(w.enc)
+	w.enc = nil
+}
+
+func small(h http.Header) bool {
+	n, err := strconv.Atoi(h.Get("Content-Length"))
+	return err == nil && n < minSize
+}
+
+func weaken(h http.Header) {
+	if tag := h.Get("ETag"); tag != "" && !strings.HasPrefix(tag, "W/") {
+		h.Set("ETag", "W/"+tag)
+	}
+}
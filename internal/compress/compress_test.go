@@ -0,0 +1,63 @@
+package compress
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHandler(t *testing.T) {
+	body := strings.Repeat(`{"flow":{"nodes":[]}}`, 100)
+	h := Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("ETag", `"v1"`)
+		io.WriteString(w, body)
+	}))
+	tests := []struct {
+		accept, coding string
+	}{
+		{"", ""},
+		{"gzip", Gzip},
+		{"gzip, deflate, br, zstd", Zstd},
+		{"zstd;q=0.5, gzip", Gzip},
+		{"zstd;q=0, gzip;q=0", ""},
+		{"*", Zstd},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		if tt.accept != "" {
+			r.Header.Set("Accept-Encoding", tt.accept)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		if got := w.Header().Get("Content-Encoding"); got != tt.coding {
+			t.Errorf("Accept-Encoding %q: Content-Encoding %q, want %q", tt.accept, got, tt.coding)
+			continue
+		}
+		switch tt.coding {
+		case "":
+			if w.Body.String() != body {
+				t.Errorf("Accept-Encoding %q: body changed", tt.accept)
+			}
+		case Gzip:
+			zr, err := gzip.NewReader(w.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, err := io.ReadAll(zr); err != nil || string(got) != body {
+				t.Errorf("Accept-Encoding %q: gunzipped %d bytes, %v", tt.accept, len(got), err)
+			}
+		}
+		if tt.coding != "" {
+			if tag := w.Header().Get("ETag"); tag != `W/"v1"` {
+				t.Errorf("Accept-Encoding %q: ETag %s, want it weak", tt.accept, tag)
+			}
+			if w.Body.Len() >= len(body) {
+				t.Errorf("Accept-Encoding %q: %d bytes compressed to %d", tt.accept, len(body), w.Body.Len())
+			}
+		}
+	}
+}
@@ -0,0 +1,309 @@
+// Package datastar implements the server half of Datastar's SSE protocol:
+// reading the signals a page sends with an action and streaming
+// datastar-patch-signals / datastar-patch-elements events back to it.
+package datastar
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/yacobolo/datastar-lit-examples/internal/requestid"
+)
+
+// Event types understood by the Datastar client.
+const (
+	EventPatchSignals  = "datastar-patch-signals"
+	EventPatchElements = "datastar-patch-elements"
+)
+
+// ElementPatchMode controls how patched elements are merged into the DOM.
+type ElementPatchMode string
+
+const (
+	ModeOuter   ElementPatchMode = "outer"
+	ModeInner   ElementPatchMode = "inner"
+	ModeReplace ElementPatchMode = "replace"
+	ModePrepend ElementPatchMode = "prepend"
+	ModeAppend  ElementPatchMode = "append"
+	ModeBefore  ElementPatchMode = "before"
+	ModeAfter   ElementPatchMode = "after"
+	ModeRemove  ElementPatchMode = "remove"
+)
+
+// SSE writes Datastar events to a single client. It is safe for concurrent
+// use, so a handler can push from several goroutines onto one stream.
+//
+// When the request has an ID, events without an explicit one are numbered
+// <request-id>-1, <request-id>-2, ..., so the Last-Event-ID a reconnecting
+// client sends leads back to the request's log lines.
+type SSE struct {
+	mu      sync.Mutex
+	w       http.ResponseWriter
+	rc      *http.ResponseController
+	ctx     context.Context
+	drained atomic.Bool
+	id      string       // request ID
+	seq     atomic.Int64 // events sent, incremented under mu
+	bytes   atomic.Int64 // atomic so Clients need not wait for a blocked write
+	streams *Streams
+
+	remote, path string
+	started      time.Time
+}
+
+// NewSSE prepares w for streaming and flushes the response headers so the
+// client sees the stream open immediately. The server's write timeout does
+// not apply to the stream.
+func NewSSE(w http.ResponseWriter, r *http.Request) *SSE {
+	h := w.Header()
+	h.Set("Content-Type", "text/event-stream")
+	h.Set("Cache-Control", "no-cache")
+	if r.ProtoMajor == 1 {
+		h.Set("Connection", "keep-alive")
+	}
+	w.WriteHeader(http.StatusOK)
+
+	s := &SSE{
+		w:       w,
+		rc:      http.NewResponseController(w),
+		ctx:     r.Context(),
+		id:      requestid.From(r.Context()),
+		remote:  r.RemoteAddr,
+		path:    r.URL.Path,
+		started: time.Now(),
+	}
+	_ = s.rc.SetWriteDeadline(time.Time{})
+	_ = s.rc.Flush()
+	register(r, s)
+	return s
+}
+
+// Done is closed when the client goes away or the stream is drained.
+func (s *SSE) Done() <-chan struct{} {
+	return s.ctx.Done()
+}
+
+// Context is the stream's context. It carries the request's values and is
+// canceled when Done is closed.
+func (s *SSE) Context() context.Context {
+	return s.ctx
+}
+
+type eventOptions struct {
+	id    string
+	retry time.Duration
+	roots []string
+}
+
+// EventOption sets fields shared by every event type.
+type EventOption func(*eventOptions)
+
+// WithEventID sets the SSE id field, which the browser echoes back as
+// Last-Event-ID when it reconnects.
+func WithEventID(id string) EventOption {
+	return func(o *eventOptions) { o.id = id }
+}
+
+// WithRetry sets the reconnect delay the client should use.
+func WithRetry(d time.Duration) EventOption {
+	return func(o *eventOptions) { o.retry = d }
+}
+
+// Send writes one raw event. Each entry of data becomes its own data: line.
+func (s *SSE) Send(event string, data []string, opts ...EventOption) error {
+	var o eventOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	seq := s.seq.Add(1)
+	if o.id == "" && s.id != "" {
+		o.id = s.id + "-" + strconv.FormatInt(seq, 10)
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("event: ")
+	buf.WriteString(event)
+	buf.WriteByte('\n')
+	if o.id != "" {
+		buf.WriteString("id: ")
+		buf.WriteString(o.id)
+		buf.WriteByte('\n')
+	}
+	if o.retry > 0 {
+		buf.WriteString("retry: ")
+		buf.WriteString(strconv.FormatInt(o.retry.Milliseconds(), 10))
+		buf.WriteByte('\n')
+	}
+	for _, line := range data {
+		buf.WriteString("data: ")
+		buf.WriteString(line)
+		buf.WriteByte('\n')
+	}
+	buf.WriteByte('\n')
+
+	n, err := s.w.Write(buf.Bytes())
+	s.bytes.Add(int64(n))
+	if err != nil {
+		return err
+	}
+	if s.streams != nil && s.streams.OnEvent != nil {
+		s.streams.OnEvent(Event{Type: event, Roots: o.roots, Bytes: buf.Len()})
+	}
+	return s.rc.Flush()
+}
+
+type patchSignalsOptions struct {
+	onlyIfMissing bool
+}
+
+// PatchSignalsOption configures a datastar-patch-signals event.
+type PatchSignalsOption func(*patchSignalsOptions)
+
+// WithOnlyIfMissing only sets signals the client does not already have.
+func WithOnlyIfMissing() PatchSignalsOption {
+	return func(o *patchSignalsOptions) { o.onlyIfMissing = true }
+}
+
+// PatchSignals merge-patches v (marshalled as JSON) into the client's
+// signals. Objects merge key by key; arrays and scalars are replaced, and
+// null removes a signal.
+func (s *SSE) PatchSignals(v any, opts ...PatchSignalsOption) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("datastar: marshal signals: %w", err)
+	}
+	return s.PatchSignalsRaw(b, opts...)
+}
+
+// PatchSignalsRaw is PatchSignals for already-encoded JSON.
+func (s *SSE) PatchSignalsRaw(signals []byte, opts ...PatchSignalsOption) error {
+	var o patchSignalsOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	var data []string
+	if o.onlyIfMissing {
+		data = append(data, "onlyIfMissing true")
+	}
+	for _, line := range strings.Split(string(signals), "\n") {
+		data = append(data, "signals "+line)
+	}
+	var roots map[string]json.RawMessage
+	_ = json.Unmarshal(signals, &roots)
+	return s.Send(EventPatchSignals, data, func(o *eventOptions) {
+		o.roots = slices.Sorted(maps.Keys(roots))
+	})
+}
+
+type patchElementsOptions struct {
+	selector          string
+	mode              ElementPatchMode
+	useViewTransition bool
+}
+
+// PatchElementsOption configures a datastar-patch-elements event.
+type PatchElementsOption func(*patchElementsOptions)
+
+// WithSelector targets elements by CSS selector instead of by the id of the
+// patched elements.
+func WithSelector(selector string) PatchElementsOption {
+	return func(o *patchElementsOptions) { o.selector = selector }
+}
+
+// WithMode sets how the elements are merged. The client default is outer.
+func WithMode(mode ElementPatchMode) PatchElementsOption {
+	return func(o *patchElementsOptions) { o.mode = mode }
+}
+
+// WithViewTransition wraps the patch in a view transition where supported.
+func WithViewTransition() PatchElementsOption {
+	return func(o *patchElementsOptions) { o.useViewTransition = true }
+}
+
+// PatchElements morphs the given HTML fragment into the page.
+func (s *SSE) PatchElements(elements string, opts ...PatchElementsOption) error {
+	var o patchElementsOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	var data []string
+	if o.selector != "" {
+		data = append(data, "selector "+o.selector)
+	}
+	if o.mode != "" && o.mode != ModeOuter {
+		data = append(data, "mode "+string(o.mode))
+	}
+	if o.useViewTransition {
+		data = append(data, "useViewTransition true")
+	}
+	if elements != "" {
+		for _, line := range strings.Split(elements, "\n") {
+			data = append(data, "elements "+line)
+		}
+	}
+	return s.Send(EventPatchElements, data)
+}
+
+// RemoveElements removes every element matching selector.
+func (s *SSE) RemoveElements(selector string) error {
+	return s.PatchElements("", WithSelector(selector), WithMode(ModeRemove))
+}
+
+// ExecuteScript runs js in the page by appending a script element to the
+// body that removes itself once it has run.
+func (s *SSE) ExecuteScript(js string) error {
+	return s.PatchElements(`<script data-effect="el.remove()">`+js+`</script>`,
+		WithSelector("body"), WithMode(ModeAppend))
+}
+
+// ErrNoSignals is returned by ReadSignals when the request carries none.
+var ErrNoSignals = errors.New("datastar: request has no signals")
+
+// MaxBodySize is the largest request body ReadSignals reads.
+const MaxBodySize = 1 << 20
+
+// ReadSignals decodes the signals Datastar sent with r into v. GET and
+// DELETE actions carry them in the datastar query parameter, everything
+// else in the JSON body. A body over MaxBodySize is an error wrapping an
+// *http.MaxBytesError.
+func ReadSignals(r *http.Request, v any) error {
+	var raw []byte
+	switch r.Method {
+	case http.MethodGet, http.MethodDelete:
+		q := r.URL.Query().Get("datastar")
+		if q == "" {
+			return ErrNoSignals
+		}
+		raw = []byte(q)
+	default:
+		b, err := io.ReadAll(http.MaxBytesReader(nil, r.Body, MaxBodySize))
+		if err != nil {
+			return fmt.Errorf("datastar: read body: %w", err)
+		}
+		if len(bytes.TrimSpace(b)) == 0 {
+			return ErrNoSignals
+		}
+		raw = b
+	}
+	if err := json.Unmarshal(raw, v); err != nil {
+		return fmt.Errorf("datastar: decode signals: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,166 @@
+package datastar
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Streams tracks the open SSE streams of a server so they can be wound
+// down together on shutdown. Streams created by NewSSE register
+// themselves when the request passed through Streams.Handler.
+type Streams struct {
+	// OnEvent, if set, is called after each event is written to one of
+	// the streams. Set it before serving.
+	OnEvent func(Event)
+
+	mu       sync.Mutex
+	open     map[*SSE]context.CancelFunc
+	draining bool
+	retry    time.Duration
+	deadline time.Time // for the final events, zero for none
+}
+
+// NewStreams returns an empty registry.
+func NewStreams() *Streams {
+	return &Streams{open: map[*SSE]context.CancelFunc{}}
+}
+
+// Event describes an event sent on a stream.
+type Event struct {
+	Type  string   // EventPatchSignals or EventPatchElements
+	Roots []string // top-level signals of a signal patch, sorted
+	Bytes int      // size on the wire, before compression
+}
+
+type streamsKey struct{}
+
+// slot is the per-request link between Handler and the SSE the request
+// opens, if any.
+type slot struct {
+	streams *Streams
+	sse     *SSE
+}
+
+// Handler makes SSE streams opened under next visible to the registry.
+// A stream ended by Drain is aborted rather than closed cleanly: the
+// client sees a dropped connection, which is what makes Datastar retry.
+func (st *Streams) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sl := &slot{streams: st}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), streamsKey{}, sl)))
+		if sl.sse != nil && sl.sse.drained.Load() {
+			panic(http.ErrAbortHandler)
+		}
+	})
+}
+
+// Len reports the number of open streams.
+func (st *Streams) Len() int {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return len(st.open)
+}
+
+// Client describes an open stream.
+type Client struct {
+	ID      string    `json:"id"` // request ID, if the request had one
+	Remote  string    `json:"remote"`
+	Path    string    `json:"path"`
+	Started time.Time `json:"started"`
+	Events  int       `json:"events"`
+	Bytes   int64     `json:"bytes"`
+}
+
+// Clients lists the open streams, oldest first.
+func (st *Streams) Clients() []Client {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	clients := make([]Client, 0, len(st.open))
+	for s := range st.open {
+		clients = append(clients, Client{s.id, s.remote, s.path, s.started, int(s.seq.Load()), s.bytes.Load()})
+	}
+	slices.SortFunc(clients, func(a, b Client) int { return a.Started.Compare(b.Started) })
+	return clients
+}
+
+// Drain tells every open stream to reconnect after retry and ends it. Any
+// stream opened afterwards is ended the same way as soon as it starts.
+// The final events are given until ctx's deadline to be written, so a
+// client that stopped reading cannot hold Drain up, and Drain returns once
+// they are written or ctx is done.
+func (st *Streams) Drain(ctx context.Context, retry time.Duration) {
+	deadline, _ := ctx.Deadline()
+	st.mu.Lock()
+	st.draining = true
+	st.retry = retry
+	st.deadline = deadline
+	open := st.open
+	st.open = map[*SSE]context.CancelFunc{}
+	st.mu.Unlock()
+
+	var wg sync.WaitGroup
+	for s, cancel := range open {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.reconnect(retry, deadline, cancel)
+		}()
+	}
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+	}
+}
+
+// register is called by NewSSE. It swaps the stream's context for one the
+// registry can cancel and unregisters the stream when the request ends.
+func register(r *http.Request, s *SSE) {
+	sl, _ := r.Context().Value(streamsKey{}).(*slot)
+	if sl == nil {
+		return
+	}
+	st := sl.streams
+	sl.sse = s
+	s.streams = st
+
+	ctx, cancel := context.WithCancel(r.Context())
+	s.ctx = ctx
+
+	st.mu.Lock()
+	if st.draining {
+		retry, deadline := st.retry, st.deadline
+		st.mu.Unlock()
+		s.reconnect(retry, deadline, cancel)
+		return
+	}
+	st.open[s] = cancel
+	st.mu.Unlock()
+
+	context.AfterFunc(r.Context(), func() {
+		st.mu.Lock()
+		delete(st.open, s)
+		st.mu.Unlock()
+		cancel()
+	})
+}
+
+// reconnect sends the final event of a drained stream. The empty signal
+// patch changes nothing on the page; its retry field sets how long the
+// client waits before reconnecting. A write still pending at deadline
+// fails, including one of the handler's that the event is queued behind.
+func (s *SSE) reconnect(retry time.Duration, deadline time.Time, cancel context.CancelFunc) {
+	s.drained.Store(true)
+	if !deadline.IsZero() {
+		_ = s.rc.SetWriteDeadline(deadline)
+	}
+	_ = s.Send(EventPatchSignals, []string{"signals {}"}, WithRetry(retry))
+	cancel()
+}
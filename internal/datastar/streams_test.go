@@ -0,0 +1,65 @@
+package datastar
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDrainStalledStream(t *testing.T) {
+	st := NewStreams()
+	ended := make(chan struct{})
+	srv := httptest.NewServer(st.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer close(ended)
+		sse := NewSSE(w, r)
+		// Write until the client's buffers are full and a write blocks.
+		filler := strings.Repeat("x", 64<<10)
+		for sse.Send("filler", []string{filler}) == nil {
+		}
+		<-sse.Done()
+	})))
+	defer srv.Close()
+
+	// A client that sends its request and never reads the response.
+	conn, err := net.Dial("tcp", srv.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	fmt.Fprintf(conn, "GET / HTTP/1.1\r\nHost: %s\r\n\r\n", srv.Listener.Addr())
+
+	var sent int64 = -1
+	for stalled := time.Now().Add(5 * time.Second); ; time.Sleep(50 * time.Millisecond) {
+		clients := st.Clients()
+		if len(clients) == 1 && clients[0].Bytes > 0 && clients[0].Bytes == sent {
+			break
+		}
+		if time.Now().After(stalled) {
+			t.Fatalf("stream never stalled: %+v", clients)
+		}
+		if len(clients) == 1 {
+			sent = clients[0].Bytes
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	st.Drain(ctx, time.Second)
+	if d := time.Since(start); d > 2*time.Second {
+		t.Errorf("Drain took %v with a 200ms deadline", d)
+	}
+	select {
+	case <-ended:
+	case <-time.After(5 * time.Second):
+		t.Fatal("handler still blocked writing after the deadline")
+	}
+	if n := st.Len(); n != 0 {
+		t.Errorf("%d streams still open after Drain", n)
+	}
+}
@@ -0,0 +1,65 @@
+// Package dot converts flow diagrams to and from Graphviz DOT.
+package dot
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/yacobolo/datastar-lit-examples/internal/signals"
+)
+
+// edgeColor is the canvas stroke, rgba(99, 102, 241, 0.6).
+const edgeColor = "#6366f199"
+
+// Encode writes f as a digraph. Nodes keep their label and color, and
+// their canvas position as a pinned pos in points, so `neato -n` draws
+// the diagram as laid out. DOT's y axis points up, so y is negated.
+func Encode(w io.Writer, f signals.Flow) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "digraph flow {")
+	fmt.Fprintf(bw, "  node [shape=circle, style=filled, fixedsize=true, width=%s, color=\"#ffffff\", penwidth=2, fontcolor=\"#ffffff\", fontsize=12, fontname=\"sans-serif\"];\n",
+		signals.FormatNumber(2*f.Config.NodeRadius/72))
+	fmt.Fprintf(bw, "  edge [color=%s, penwidth=%s];\n", quote(edgeColor), signals.FormatNumber(f.Config.LineWidth))
+	for _, n := range f.Nodes {
+		color := n.Color
+		if color == "" {
+			color = signals.DefaultNodeColor
+		}
+		fmt.Fprintf(bw, "  %s [label=%s, fillcolor=%s, pos=%s];\n",
+			quote(n.ID), quote(n.Label), quote(color), quote(signals.FormatNumber(n.X)+","+signals.FormatNumber(-n.Y)+"!"))
+	}
+	ids := map[string]bool{}
+	for _, n := range f.Nodes {
+		ids[n.ID] = true
+	}
+	for _, e := range f.Edges {
+		if !ids[e.Source] || !ids[e.Target] {
+			continue
+		}
+		fmt.Fprintf(bw, "  %s -> %s [id=%s];\n", quote(e.Source), quote(e.Target), quote(e.ID))
+	}
+	fmt.Fprintln(bw, "}")
+	return bw.Flush()
+}
+
+// quote returns s as a DOT double-quoted string.
+func quote(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"', '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+		default:
+			b.WriteRune(r)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
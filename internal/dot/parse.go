@@ -0,0 +1,655 @@
+package dot
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/yacobolo/datastar-lit-examples/internal/flowgraph"
+	"github.com/yacobolo/datastar-lit-examples/internal/signals"
+)
+
+// Error is a syntax error in DOT source. Line and Col are 1-based; Col
+// counts runes.
+type Error struct {
+	Line, Col int
+	Msg       string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%d:%d: %s", e.Line, e.Col, e.Msg)
+}
+
+// Parse reads a graph or digraph into a flow. It understands node, edge
+// and attribute statements, subgraphs (which are flattened), ports (which
+// are ignored) and the label, color, fillcolor and pos node attributes;
+// other attributes are skipped.
+//
+// Nodes get IDs 1, 2, ... in the order they are first mentioned, and
+// edges likewise. A graph with more than flowgraph.MaxNodes nodes or
+// flowgraph.MaxEdges edges, or subgraphs nested more than MaxDepth deep,
+// is an error. If every node has a pos, as in files written by Encode, the
+// positions are kept; otherwise the graph is laid out with
+// flowgraph.Layered, top to bottom unless rankdir says LR or RL. The
+// result's Config is zero.
+func Parse(src []byte, o flowgraph.Options) (signals.Flow, error) {
+	p := &parser{lex: lexer{src: string(src), line: 1, col: 1}, index: map[string]int{}}
+	p.next()
+	if err := p.graph(); err != nil {
+		return signals.Flow{}, err
+	}
+	return p.flow(o), nil
+}
+
+// MaxDepth is how deeply subgraphs may nest. The parser recurses once per
+// level, so without a limit a run of braces could exhaust the stack.
+const MaxDepth = 64
+
+type parser struct {
+	lex      lexer
+	tok      token
+	depth    int // subgraphs open around the current token
+	directed bool
+	rankdir  string
+	nodes    []*node
+	index    map[string]int // node name to position in nodes
+	edges    [][2]int
+}
+
+type node struct {
+	name, label, color, fill string
+	x, y                     float64
+	hasPos                   bool
+}
+
+// attrs holds the node attributes this package reads, for defaults.
+type attrs map[string]string
+
+func (p *parser) next() { p.tok = p.lex.next() }
+
+func (p *parser) errorf(t token, format string, args ...any) error {
+	return &Error{Line: t.line, Col: t.col, Msg: fmt.Sprintf(format, args...)}
+}
+
+// keyword reports whether the current token is the unquoted keyword kw.
+func (p *parser) keyword(kw string) bool {
+	return p.tok.kind == tokID && !p.tok.quoted && strings.EqualFold(p.tok.text, kw)
+}
+
+func (p *parser) expect(kind tokKind) (token, error) {
+	t := p.tok
+	if t.kind != kind {
+		return t, p.unexpected(kind.String())
+	}
+	p.next()
+	return t, nil
+}
+
+// unexpected reports that the current token is not what was wanted, or
+// the lexer's error if it could not read one.
+func (p *parser) unexpected(want string) error {
+	if p.tok.kind == tokError {
+		return p.errorf(p.tok, "%s", p.tok.text)
+	}
+	return p.errorf(p.tok, "expected %s, got %s", want, p.tok)
+}
+
+func (p *parser) graph() error {
+	if p.keyword("strict") {
+		p.next()
+	}
+	switch {
+	case p.keyword("digraph"):
+		p.directed = true
+	case p.keyword("graph"):
+	default:
+		return p.unexpected("graph or digraph")
+	}
+	p.next()
+	if p.tok.kind == tokID {
+		p.next()
+	}
+	if _, err := p.expect(tokLBrace); err != nil {
+		return err
+	}
+	if _, err := p.stmts(attrs{}); err != nil {
+		return err
+	}
+	if _, err := p.expect(tokRBrace); err != nil {
+		return err
+	}
+	if p.tok.kind != tokEOF {
+		return p.unexpected("end of input after the graph")
+	}
+	return nil
+}
+
+// stmts parses statements up to a closing brace, with defaults as the
+// node attributes in scope, and returns the nodes they mention.
+func (p *parser) stmts(defaults attrs) ([]int, error) {
+	defaults = copyAttrs(defaults)
+	var mentioned []int
+	for p.tok.kind != tokRBrace {
+		if p.tok.kind == tokEOF {
+			return nil, p.errorf(p.tok, "missing } at end of input")
+		}
+		ids, err := p.stmt(defaults)
+		if err != nil {
+			return nil, err
+		}
+		mentioned = append(mentioned, ids...)
+		if p.tok.kind == tokSemi {
+			p.next()
+		}
+	}
+	return mentioned, nil
+}
+
+func (p *parser) stmt(defaults attrs) ([]int, error) {
+	switch {
+	case p.keyword("node"), p.keyword("edge"), p.keyword("graph"):
+		kind := strings.ToLower(p.tok.text)
+		p.next()
+		a, err := p.attrList(true)
+		if err != nil {
+			return nil, err
+		}
+		switch kind {
+		case "node":
+			for k, v := range a {
+				defaults[k] = v
+			}
+		case "graph":
+			p.graphAttrs(a)
+		}
+		return nil, nil
+	case p.tok.kind == tokID && !p.keyword("subgraph"):
+		name := p.tok
+		p.next()
+		if p.tok.kind == tokEq {
+			p.next()
+			value, err := p.expect(tokID)
+			if err != nil {
+				return nil, err
+			}
+			p.graphAttrs(attrs{strings.ToLower(name.text): value.text})
+			return nil, nil
+		}
+		if err := p.port(); err != nil {
+			return nil, err
+		}
+		id, err := p.node(name, defaults)
+		if err != nil {
+			return nil, err
+		}
+		return p.edgeOrNode([]int{id}, defaults)
+	case p.keyword("subgraph"), p.tok.kind == tokLBrace:
+		ids, err := p.subgraph(defaults)
+		if err != nil {
+			return nil, err
+		}
+		return p.edgeOrNode(ids, defaults)
+	}
+	return nil, p.unexpected("a statement")
+}
+
+// edgeOrNode continues a statement that started with the nodes in ids:
+// either a chain of edges or, if no edge operator follows, a node
+// statement.
+func (p *parser) edgeOrNode(ids []int, defaults attrs) ([]int, error) {
+	mentioned := ids
+	from := ids
+	isEdge := p.tok.kind == tokEdgeOp
+	for p.tok.kind == tokEdgeOp {
+		if p.directed != (p.tok.text == "->") {
+			want := "--"
+			if p.directed {
+				want = "->"
+			}
+			return nil, p.errorf(p.tok, "%s in a %s, use %s", p.tok.text, p.kindName(), want)
+		}
+		op := p.tok
+		p.next()
+		var to []int
+		switch {
+		case p.keyword("subgraph"), p.tok.kind == tokLBrace:
+			var err error
+			if to, err = p.subgraph(defaults); err != nil {
+				return nil, err
+			}
+		case p.tok.kind == tokID:
+			name := p.tok
+			p.next()
+			if err := p.port(); err != nil {
+				return nil, err
+			}
+			id, err := p.node(name, defaults)
+			if err != nil {
+				return nil, err
+			}
+			to = []int{id}
+		default:
+			return nil, p.unexpected("a node or subgraph after the edge")
+		}
+		for _, s := range from {
+			for _, t := range to {
+				if len(p.edges) == flowgraph.MaxEdges {
+					return nil, p.errorf(op, "too many edges, at most %d are allowed", flowgraph.MaxEdges)
+				}
+				p.edges = append(p.edges, [2]int{s, t})
+			}
+		}
+		mentioned = append(mentioned, to...)
+		from = to
+	}
+
+	if p.tok.kind == tokLBrack {
+		a, err := p.attrList(false)
+		if err != nil {
+			return nil, err
+		}
+		if !isEdge {
+			for _, id := range ids {
+				p.nodes[id].apply(a)
+			}
+		}
+	}
+	return mentioned, nil
+}
+
+func (p *parser) kindName() string {
+	if p.directed {
+		return "digraph"
+	}
+	return "graph"
+}
+
+// subgraph parses [subgraph [ID]] { stmts } and returns its nodes.
+func (p *parser) subgraph(defaults attrs) ([]int, error) {
+	if p.keyword("subgraph") {
+		p.next()
+		if p.tok.kind == tokID {
+			p.next()
+		}
+	}
+	brace, err := p.expect(tokLBrace)
+	if err != nil {
+		return nil, err
+	}
+	if p.depth == MaxDepth {
+		return nil, p.errorf(brace, "subgraphs nested too deeply, at most %d levels are allowed", MaxDepth)
+	}
+	p.depth++
+	ids, err := p.stmts(defaults)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := p.expect(tokRBrace); err != nil {
+		return nil, err
+	}
+	p.depth--
+	return ids, nil
+}
+
+// port skips :port[:compass] after a node ID.
+func (p *parser) port() error {
+	for range 2 {
+		if p.tok.kind != tokColon {
+			return nil
+		}
+		p.next()
+		if _, err := p.expect(tokID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// attrList parses one or more [a=b, ...] lists. If required, there has to
+// be at least one.
+func (p *parser) attrList(required bool) (attrs, error) {
+	a := attrs{}
+	if required && p.tok.kind != tokLBrack {
+		return nil, p.unexpected("[")
+	}
+	for p.tok.kind == tokLBrack {
+		p.next()
+		for p.tok.kind != tokRBrack {
+			key, err := p.expect(tokID)
+			if err != nil {
+				return nil, err
+			}
+			value := "true"
+			if p.tok.kind == tokEq {
+				p.next()
+				v, err := p.expect(tokID)
+				if err != nil {
+					return nil, err
+				}
+				value = v.text
+			}
+			a[strings.ToLower(key.text)] = value
+			if p.tok.kind == tokComma || p.tok.kind == tokSemi {
+				p.next()
+			}
+		}
+		p.next()
+	}
+	return a, nil
+}
+
+func (p *parser) graphAttrs(a attrs) {
+	if v, ok := a["rankdir"]; ok {
+		p.rankdir = strings.ToUpper(v)
+	}
+}
+
+// node returns the index of the node named by t, creating it with
+// defaults.
+func (p *parser) node(t token, defaults attrs) (int, error) {
+	if i, ok := p.index[t.text]; ok {
+		return i, nil
+	}
+	if len(p.nodes) == flowgraph.MaxNodes {
+		return 0, p.errorf(t, "too many nodes, at most %d are allowed", flowgraph.MaxNodes)
+	}
+	n := &node{name: t.text}
+	n.apply(defaults)
+	p.index[t.text] = len(p.nodes)
+	p.nodes = append(p.nodes, n)
+	return len(p.nodes) - 1, nil
+}
+
+var htmlTag = regexp.MustCompile(`<[^>]*>`)
+
+func (n *node) apply(a attrs) {
+	if v, ok := a["label"]; ok {
+		n.label = v
+	}
+	if v, ok := a["color"]; ok {
+		n.color = v
+	}
+	if v, ok := a["fillcolor"]; ok {
+		n.fill = v
+	}
+	if v, ok := a["pos"]; ok {
+		x, y, ok := strings.Cut(strings.TrimSuffix(v, "!"), ",")
+		fx, err1 := strconv.ParseFloat(strings.TrimSpace(x), 64)
+		fy, err2 := strconv.ParseFloat(strings.TrimSpace(y), 64)
+		if ok && err1 == nil && err2 == nil {
+			n.x, n.y, n.hasPos = fx, -fy, true
+		}
+	}
+}
+
+// text is the label as the canvas shows it: escapes resolved, HTML tags
+// dropped and lines joined.
+func (n *node) text() string {
+	label := n.label
+	if label == "" || label == `\N` {
+		return n.name
+	}
+	label = strings.NewReplacer(`\\`, `\`, `\N`, n.name, `\G`, "", `\E`, "", `\T`, "", `\H`, "",
+		`\n`, " ", `\l`, " ", `\r`, " ").Replace(label)
+	label = htmlTag.ReplaceAllString(label, "")
+	return strings.Join(strings.Fields(label), " ")
+}
+
+func (p *parser) flow(o flowgraph.Options) signals.Flow {
+	f := signals.Flow{Nodes: []signals.FlowNode{}, Edges: []signals.FlowEdge{}}
+	positioned := len(p.nodes) > 0
+	for i, n := range p.nodes {
+		color, ok := signals.ParseColor(n.fill)
+		if !ok {
+			color, _ = signals.ParseColor(n.color)
+		}
+		f.Nodes = append(f.Nodes, signals.FlowNode{
+			ID:    strconv.Itoa(i + 1),
+			Label: n.text(),
+			X:     n.x,
+			Y:     n.y,
+			Color: color,
+		})
+		positioned = positioned && n.hasPos
+	}
+	for i, e := range p.edges {
+		f.Edges = append(f.Edges, signals.FlowEdge{
+			ID:     strconv.Itoa(i + 1),
+			Source: strconv.Itoa(e[0] + 1),
+			Target: strconv.Itoa(e[1] + 1),
+		})
+	}
+	if positioned {
+		for i := range f.Nodes {
+			f.Nodes[i].X, f.Nodes[i].Y = math.Round(f.Nodes[i].X), math.Round(f.Nodes[i].Y)
+		}
+		return f
+	}
+	o.Vertical = p.rankdir != "LR" && p.rankdir != "RL"
+	f.Nodes = flowgraph.Layout(flowgraph.Layered, f, o, nil)
+	return f
+}
+
+func copyAttrs(a attrs) attrs {
+	c := make(attrs, len(a))
+	for k, v := range a {
+		c[k] = v
+	}
+	return c
+}
+
+type tokKind int
+
+const (
+	tokEOF tokKind = iota
+	tokError
+	tokID
+	tokLBrace
+	tokRBrace
+	tokLBrack
+	tokRBrack
+	tokSemi
+	tokComma
+	tokEq
+	tokColon
+	tokEdgeOp
+)
+
+func (k tokKind) String() string {
+	return [...]string{"end of input", "error", "an ID", "{", "}", "[", "]", ";", ",", "=", ":", "an edge operator"}[k]
+}
+
+type token struct {
+	kind      tokKind
+	text      string
+	quoted    bool
+	line, col int
+}
+
+func (t token) String() string {
+	if t.kind == tokEOF {
+		return t.kind.String()
+	}
+	return strconv.Quote(t.text)
+}
+
+var punctuation = map[rune]tokKind{
+	'{': tokLBrace, '}': tokRBrace, '[': tokLBrack, ']': tokRBrack,
+	';': tokSemi, ',': tokComma, '=': tokEq, ':': tokColon,
+}
+
+// lexer splits DOT source into tokens, skipping whitespace, comments and
+// lines starting with # (C preprocessor output).
+type lexer struct {
+	src       string
+	pos       int
+	line, col int
+}
+
+func (l *lexer) peek() rune {
+	r, _ := utf8.DecodeRuneInString(l.src[l.pos:])
+	return r
+}
+
+func (l *lexer) advance() rune {
+	r, size := utf8.DecodeRuneInString(l.src[l.pos:])
+	l.pos += size
+	if r == '\n' {
+		l.line++
+		l.col = 1
+	} else {
+		l.col++
+	}
+	return r
+}
+
+func (l *lexer) skip() {
+	for l.pos < len(l.src) {
+		rest := l.src[l.pos:]
+		switch {
+		case unicode.IsSpace(l.peek()):
+			l.advance()
+		case strings.HasPrefix(rest, "//"), l.col == 1 && rest[0] == '#':
+			for l.pos < len(l.src) && l.peek() != '\n' {
+				l.advance()
+			}
+		case strings.HasPrefix(rest, "/*"):
+			l.advance()
+			l.advance()
+			for l.pos < len(l.src) && !strings.HasPrefix(l.src[l.pos:], "*/") {
+				l.advance()
+			}
+			if l.pos < len(l.src) {
+				l.advance()
+				l.advance()
+			}
+		default:
+			return
+		}
+	}
+}
+
+func (l *lexer) next() token {
+	l.skip()
+	t := token{line: l.line, col: l.col}
+	if l.pos >= len(l.src) {
+		t.kind = tokEOF
+		return t
+	}
+	rest := l.src[l.pos:]
+	if strings.HasPrefix(rest, "->") || strings.HasPrefix(rest, "--") {
+		l.advance()
+		l.advance()
+		t.kind, t.text = tokEdgeOp, rest[:2]
+		return t
+	}
+	if k, ok := punctuation[l.peek()]; ok {
+		t.kind, t.text = k, string(l.advance())
+		return t
+	}
+
+	r := l.peek()
+	switch {
+	case r == '"':
+		return l.quoted(t)
+	case r == '<':
+		return l.html(t)
+	case r == '-' || r == '.' || unicode.IsDigit(r):
+		start := l.pos
+		if r == '-' {
+			l.advance()
+		}
+		for l.pos < len(l.src) && (unicode.IsDigit(l.peek()) || l.peek() == '.') {
+			l.advance()
+		}
+		t.kind, t.text = tokID, l.src[start:l.pos]
+		if _, err := strconv.ParseFloat(t.text, 64); err != nil {
+			t.kind, t.text = tokError, fmt.Sprintf("invalid number %q", t.text)
+		}
+		return t
+	case r == '_' || unicode.IsLetter(r) || r >= 0x80:
+		start := l.pos
+		for l.pos < len(l.src) {
+			r := l.peek()
+			if r != '_' && !unicode.IsLetter(r) && !unicode.IsDigit(r) && r < 0x80 {
+				break
+			}
+			l.advance()
+		}
+		t.kind, t.text = tokID, l.src[start:l.pos]
+		return t
+	}
+	t.kind, t.text = tokError, fmt.Sprintf("unexpected character %q", r)
+	return t
+}
+
+// quoted reads a double-quoted string, joining "a" + "b" concatenations.
+// Only \" is unescaped; other escapes such as \n are label escapes and
+// are kept for the caller.
+func (l *lexer) quoted(t token) token {
+	var b strings.Builder
+	for {
+		l.advance() // opening quote
+		closed := false
+		for l.pos < len(l.src) {
+			r := l.advance()
+			if r == '"' {
+				closed = true
+				break
+			}
+			if r == '\\' && l.pos < len(l.src) {
+				switch next := l.peek(); next {
+				case '"':
+					b.WriteRune(l.advance())
+					continue
+				case '\n':
+					l.advance() // line continuation
+					continue
+				}
+			}
+			b.WriteRune(r)
+		}
+		if !closed {
+			t.kind, t.text = tokError, "string not terminated"
+			return t
+		}
+		// Look past whitespace for a + joining another string.
+		save := *l
+		l.skip()
+		if l.pos < len(l.src) && l.peek() == '+' {
+			l.advance()
+			l.skip()
+			if l.pos < len(l.src) && l.peek() == '"' {
+				continue
+			}
+		}
+		*l = save
+		break
+	}
+	t.kind, t.text, t.quoted = tokID, b.String(), true
+	return t
+}
+
+// html reads an HTML string, <...> with balanced angle brackets.
+func (l *lexer) html(t token) token {
+	start := l.pos
+	depth := 0
+	for l.pos < len(l.src) {
+		switch l.advance() {
+		case '<':
+			depth++
+		case '>':
+			depth--
+		}
+		if depth == 0 {
+			t.kind, t.text, t.quoted = tokID, l.src[start+1:l.pos-1], true
+			return t
+		}
+	}
+	t.kind, t.text = tokError, "HTML string not terminated"
+	return t
+}
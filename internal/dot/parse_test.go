@@ -0,0 +1,235 @@
+package dot
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/yacobolo/datastar-lit-examples/internal/flowgraph"
+	"github.com/yacobolo/datastar-lit-examples/internal/signals"
+)
+
+// summary renders a flow's nodes as label/color and its edges as
+// source->target, leaving out the computed positions.
+func summary(f signals.Flow) (nodes, edges []string) {
+	for i, n := range f.Nodes {
+		if want := fmt.Sprint(i + 1); n.ID != want {
+			nodes = append(nodes, "bad ID "+n.ID)
+		}
+		nodes = append(nodes, n.Label+"/"+n.Color)
+	}
+	for i, e := range f.Edges {
+		if want := fmt.Sprint(i + 1); e.ID != want {
+			edges = append(edges, "bad ID "+e.ID)
+		}
+		edges = append(edges, e.Source+"->"+e.Target)
+	}
+	return nodes, edges
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name  string
+		src   string
+		nodes []string
+		edges []string
+	}{
+		{
+			name:  "chain",
+			src:   `digraph { a -> b -> c }`,
+			nodes: []string{"a/", "b/", "c/"},
+			edges: []string{"1->2", "2->3"},
+		},
+		{
+			name:  "undirected strict graph with semicolons",
+			src:   "strict graph G { a -- b; b -- c; }",
+			nodes: []string{"a/", "b/", "c/"},
+			edges: []string{"1->2", "2->3"},
+		},
+		{
+			name:  "node statements and attributes",
+			src:   `digraph { b [label="Second", color=red]; a [fillcolor="#0F0", color=blue]; a -> b }`,
+			nodes: []string{"Second/#ff0000", "a/#00ff00"},
+			edges: []string{"2->1"},
+		},
+		{
+			name:  "node defaults are scoped to their subgraph",
+			src:   "digraph {\n  node [color=\"#123456\"]\n  a\n  subgraph s { node [color=white] b }\n  c\n}",
+			nodes: []string{"a/#123456", "b/#ffffff", "c/#123456"},
+		},
+		{
+			name:  "subgraphs as edge ends",
+			src:   `digraph { {a b} -> {c d} }`,
+			nodes: []string{"a/", "b/", "c/", "d/"},
+			edges: []string{"1->3", "1->4", "2->3", "2->4"},
+		},
+		{
+			name:  "edge attributes do not touch nodes",
+			src:   `digraph { a -> b [label="edge", color=red] }`,
+			nodes: []string{"a/", "b/"},
+			edges: []string{"1->2"},
+		},
+		{
+			name: "comments, ports and graph attributes",
+			src: "/* head */ digraph {\n" +
+				"  // line comment\n" +
+				"# preprocessor line\n" +
+				"  rankdir=LR\n" +
+				"  graph [splines=true]\n" +
+				"  a:n -> b:s:w\n" +
+				"}",
+			nodes: []string{"a/", "b/"},
+			edges: []string{"1->2"},
+		},
+		{
+			name:  "quoted IDs, concatenation and escapes",
+			src:   `digraph { "x y" [label="one" + " two"]; "q\"uote" [label="\N!\nnext"]; n [label=<<b>bold</b>>]; "x y" -> "q\"uote" }`,
+			nodes: []string{"one two/", `q"uote! next/`, "bold/"},
+			edges: []string{"1->2"},
+		},
+		{
+			name:  "numerals are IDs",
+			src:   `digraph { 1 -> -2.5 -> .5 }`,
+			nodes: []string{"1/", "-2.5/", ".5/"},
+			edges: []string{"1->2", "2->3"},
+		},
+		{
+			name:  "subgraphs nested to the limit",
+			src:   "digraph { " + strings.Repeat("{", MaxDepth) + "a" + strings.Repeat("}", MaxDepth) + " }",
+			nodes: []string{"a/"},
+		},
+		{
+			name:  "keywords in any case",
+			src:   `DiGraph { Node [color=red]; a }`,
+			nodes: []string{"a/#ff0000"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := Parse([]byte(tt.src), flowgraph.DefaultOptions)
+			if err != nil {
+				t.Fatal(err)
+			}
+			nodes, edges := summary(f)
+			if !reflect.DeepEqual(nodes, tt.nodes) || !reflect.DeepEqual(edges, tt.edges) {
+				t.Errorf("Parse() = %q %q, want %q %q", nodes, edges, tt.nodes, tt.edges)
+			}
+			if f.Config != (signals.FlowConfig{}) {
+				t.Errorf("Config = %+v, want zero", f.Config)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{"empty", ``, `1:1: expected graph or digraph, got end of input`},
+		{"not a graph", `flowchart LR`, `1:1: expected graph or digraph, got "flowchart"`},
+		{"undirected edge in digraph", `digraph { a -- b }`, `1:13: -- in a digraph, use ->`},
+		{"directed edge in graph", "graph {\n  a -> b\n}", `2:5: -> in a graph, use --`},
+		{"missing close", "digraph {\n  a -> b\n", `3:1: missing } at end of input`},
+		{"unterminated string", `digraph { "abc }`, `1:11: string not terminated`},
+		{"edge without target", `digraph { a -> ; }`, `1:16: expected a node or subgraph after the edge, got ";"`},
+		{"bad attribute", `digraph { a [label=] }`, `1:20: expected an ID, got "]"`},
+		{"trailing input", `digraph { } x`, `1:13: expected end of input after the graph, got "x"`},
+		{"deep nesting", "digraph {\n" + strings.Repeat("{", MaxDepth+1), fmt.Sprintf("2:%d: subgraphs nested too deeply, at most %d levels are allowed", MaxDepth+1, MaxDepth)},
+		{"deep nesting attack", "digraph {" + strings.Repeat("{", 1<<20) + "}", fmt.Sprintf("1:%d: subgraphs nested too deeply, at most %d levels are allowed", 10+MaxDepth, MaxDepth)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse([]byte(tt.src), flowgraph.DefaultOptions)
+			var perr *Error
+			if !errors.As(err, &perr) {
+				t.Fatalf("Parse() error = %v, want an *Error", err)
+			}
+			if err.Error() != tt.want {
+				t.Errorf("Parse() error = %q, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseLimits(t *testing.T) {
+	var names []string
+	for i := range flowgraph.MaxNodes + 1 {
+		names = append(names, fmt.Sprintf("n%d", i))
+	}
+	nodes := "digraph {\n" + strings.Join(names, " ") + "\n}"
+	_, err := Parse([]byte(nodes), flowgraph.DefaultOptions)
+	want := fmt.Sprintf("2:%d: too many nodes, at most %d are allowed",
+		len(strings.Join(names[:flowgraph.MaxNodes], " "))+2, flowgraph.MaxNodes)
+	if err == nil || err.Error() != want {
+		t.Errorf("%d nodes: error %v, want %q", len(names), err, want)
+	}
+
+	group := "{" + strings.Join(names[:50], " ") + "}"
+	edges := "digraph {\n" + group + " -> " + group + "\n}"
+	_, err = Parse([]byte(edges), flowgraph.DefaultOptions)
+	want = fmt.Sprintf("2:%d: too many edges, at most %d are allowed", len(group)+2, flowgraph.MaxEdges)
+	if err == nil || err.Error() != want {
+		t.Errorf("50×50 edges: error %v, want %q", err, want)
+	}
+}
+
+func TestParseLayout(t *testing.T) {
+	// Without positions the graph is laid out, top to bottom by default.
+	f, err := Parse([]byte(`digraph { a -> b }`), flowgraph.DefaultOptions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a, b := f.Nodes[0], f.Nodes[1]; a.X != b.X || a.Y >= b.Y {
+		t.Errorf("digraph laid out as %+v, want b below a", f.Nodes)
+	}
+	f, err = Parse([]byte(`digraph { rankdir=LR; a -> b }`), flowgraph.DefaultOptions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a, b := f.Nodes[0], f.Nodes[1]; a.Y != b.Y || a.X >= b.X {
+		t.Errorf("rankdir=LR laid out as %+v, want b right of a", f.Nodes)
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	flow := signals.Default().Flow
+	flow.Nodes = append(flow.Nodes, signals.FlowNode{ID: "extra", Label: `Quote " and \ slash`, X: 12, Y: 34})
+
+	var buf bytes.Buffer
+	if err := Encode(&buf, flow); err != nil {
+		t.Fatal(err)
+	}
+	got, err := Parse(buf.Bytes(), flowgraph.DefaultOptions)
+	if err != nil {
+		t.Fatalf("Parse(Encode()) error = %v\n%s", err, buf.Bytes())
+	}
+
+	if len(got.Nodes) != len(flow.Nodes) {
+		t.Fatalf("got %d nodes, want %d", len(got.Nodes), len(flow.Nodes))
+	}
+	ids := map[string]string{}
+	for i, n := range flow.Nodes {
+		color := n.Color
+		if color == "" {
+			color = signals.DefaultNodeColor
+		}
+		g := got.Nodes[i]
+		if g.Label != n.Label || g.Color != color || g.X != n.X || g.Y != n.Y {
+			t.Errorf("node %d = %+v, want %+v with color %s", i, g, n, color)
+		}
+		ids[n.ID] = g.ID
+	}
+	if len(got.Edges) != len(flow.Edges) {
+		t.Fatalf("got %d edges, want %d", len(got.Edges), len(flow.Edges))
+	}
+	for i, e := range flow.Edges {
+		if g := got.Edges[i]; g.Source != ids[e.Source] || g.Target != ids[e.Target] {
+			t.Errorf("edge %d = %s->%s, want %s->%s", i, g.Source, g.Target, ids[e.Source], ids[e.Target])
+		}
+	}
+}
@@ -0,0 +1,292 @@
+// Package fingerprint finds the local assets a page references, directly or
+// through other assets, and names each after a hash of its content:
+// demo/dist/components.js becomes demo/dist/components.1a2b3c4d.js. The
+// references are rewritten on the way, so a file's hash covers the hashed
+// names of everything it loads and a change anywhere renames every file
+// that leads to it.
+package fingerprint
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"mime"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/yacobolo/datastar-lit-examples/internal/compress"
+)
+
+// HashLen is the number of hex digits of the content hash in a name.
+const HashLen = 8
+
+// Asset is one referenced file.
+type Asset struct {
+	Path string // original path, relative to the site root
+	Name string // fingerprinted path
+	Data []byte // content with its own references rewritten
+	Sum  string // hex SHA-256 of Data
+
+	size    int64     // of the file when it was read
+	modTime time.Time // likewise
+}
+
+// Result is a fingerprinted page and everything it loads.
+type Result struct {
+	Page   []byte
+	Assets []*Asset // sorted by Path
+}
+
+// references matches relative paths in quotes, such as the importmap
+// entries of a page or the import specifiers of a bundle, unquoted CSS
+// url()s, and source map comments. Matches that name no file in the site
+// are left alone, so stray strings in bundled code are harmless.
+var references = regexp.MustCompile(`["'](\.\.?/[^"'\s?#]+)["']|url\((\.\.?/[^"'\s?#)]+)\)|sourceMappingURL=([^\s*?#]+)`)
+
+// Page fingerprints the assets page, a document at name in fsys, refers to.
+// Unlike references inside assets, every one in the page must exist. Source
+// maps are copied as they are; their sources are not part of the site.
+func Page(fsys fs.FS, name string, page []byte) (*Result, error) {
+	w := newWalker(fsys)
+	out, err := w.rewrite(name, page, true)
+	if err != nil {
+		return nil, err
+	}
+	return w.result(out), nil
+}
+
+// Names maps original asset paths to fingerprinted ones.
+func (r *Result) Names() map[string]string {
+	m := make(map[string]string, len(r.Assets))
+	for _, a := range r.Assets {
+		m[a.Path] = a.Name
+	}
+	return m
+}
+
+type walker struct {
+	fsys     fs.FS
+	assets   map[string]*Asset
+	visiting map[string]bool
+}
+
+func newWalker(fsys fs.FS) *walker {
+	return &walker{fsys: fsys, assets: map[string]*Asset{}, visiting: map[string]bool{}}
+}
+
+// result collects the page and the assets walked so far.
+func (w *walker) result(page []byte) *Result {
+	r := &Result{Page: page}
+	for _, a := range w.assets {
+		r.Assets = append(r.Assets, a)
+	}
+	slices.SortFunc(r.Assets, func(a, b *Asset) int { return strings.Compare(a.Path, b.Path) })
+	return r
+}
+
+// rewrite replaces every reference in data, which belongs to the file at
+// name, with the fingerprinted name of its target. A strict rewrite fails
+// on references to missing files.
+func (w *walker) rewrite(name string, data []byte, strict bool) ([]byte, error) {
+	var err error
+	out := references.ReplaceAllFunc(data, func(m []byte) []byte {
+		if err != nil {
+			return m
+		}
+		sub := references.FindSubmatch(m)
+		ref := string(slices.Concat(sub[1], sub[2], sub[3]))
+		target := path.Join(path.Dir(name), ref)
+		if !fs.ValidPath(target) {
+			return m
+		}
+		var a *Asset
+		if a, err = w.asset(target); a == nil || err != nil {
+			if err == nil && strict {
+				err = fmt.Errorf("fingerprint: %s refers to missing %s", name, target)
+			}
+			return m
+		}
+		hashed := ref[:len(ref)-len(path.Base(ref))] + path.Base(a.Name)
+		return []byte(strings.Replace(string(m), ref, hashed, 1))
+	})
+	return out, err
+}
+
+// asset fingerprints the file at name, or returns nil if there is none.
+func (w *walker) asset(name string) (*Asset, error) {
+	if a, ok := w.assets[name]; ok {
+		return a, nil
+	}
+	if w.visiting[name] {
+		return nil, fmt.Errorf("fingerprint: %s is part of a reference cycle", name)
+	}
+	// Stat before reading, so a file written in between looks changed the
+	// next time rather than never.
+	fi, err := fs.Stat(w.fsys, name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !fi.Mode().IsRegular() {
+		// Directories and the like are not assets either.
+		return nil, nil
+	}
+	data, err := fs.ReadFile(w.fsys, name)
+	if err != nil {
+		return nil, err
+	}
+
+	if path.Ext(name) != ".map" {
+		w.visiting[name] = true
+		data, err = w.rewrite(name, data, false)
+		delete(w.visiting, name)
+		if err != nil {
+			return nil, err
+		}
+	}
+	sum := sha256.Sum256(data)
+	a := &Asset{
+		Path: name, Name: Name(name, sum[:]), Data: data, Sum: hex.EncodeToString(sum[:]),
+		size: fi.Size(), modTime: fi.ModTime(),
+	}
+	w.assets[name] = a
+	return a, nil
+}
+
+// Name inserts the start of sum before the extension of name.
+func Name(name string, sum []byte) string {
+	ext := path.Ext(name)
+	return strings.TrimSuffix(name, ext) + "." + hex.EncodeToString(sum)[:HashLen] + ext
+}
+
+// Table serves the assets of the latest Result by fingerprinted name. It
+// is safe for concurrent use.
+type Table struct {
+	mu     sync.RWMutex
+	byName map[string]*Asset
+	byPath map[string]*Asset
+	gzip   map[string][]byte // by fingerprinted name
+}
+
+// Update replaces the table's contents with the assets of r. Compressible
+// assets are gzipped here, once per content, so they can be sent
+// compressed without doing the work per request.
+func (t *Table) Update(r *Result) {
+	t.mu.RLock()
+	oldNames, oldGzip := t.byName, t.gzip
+	t.mu.RUnlock()
+
+	byName := make(map[string]*Asset, len(r.Assets))
+	byPath := make(map[string]*Asset, len(r.Assets))
+	gz := make(map[string][]byte, len(r.Assets))
+	for _, a := range r.Assets {
+		byName[a.Name] = a
+		byPath[a.Path] = a
+		if old, ok := oldNames[a.Name]; ok && old.Sum == a.Sum {
+			if data, ok := oldGzip[a.Name]; ok {
+				gz[a.Name] = data
+			}
+			continue
+		}
+		if data := gzipped(a); data != nil {
+			gz[a.Name] = data
+		}
+	}
+	t.mu.Lock()
+	t.byName, t.byPath, t.gzip = byName, byPath, gz
+	t.mu.Unlock()
+}
+
+// gzipped returns a's content compressed, or nil if its type does not
+// compress or compressing does not make it smaller.
+func gzipped(a *Asset) []byte {
+	if !compress.Compressible(mime.TypeByExtension(path.Ext(a.Path))) {
+		return nil
+	}
+	var buf bytes.Buffer
+	zw, _ := gzip.NewWriterLevel(&buf, gzip.BestCompression)
+	zw.Write(a.Data)
+	zw.Close()
+	if buf.Len() >= len(a.Data) {
+		return nil
+	}
+	return buf.Bytes()
+}
+
+// Page fingerprints page like the function Page and serves the result from
+// t. The assets already in t are reused as long as none of their files has
+// changed size or modification time since it was read; otherwise they are
+// all read again. Only new references are read either way, so rendering a
+// page costs a stat per asset rather than reading and hashing them all.
+func (t *Table) Page(fsys fs.FS, name string, page []byte) ([]byte, error) {
+	t.mu.RLock()
+	cached := t.byPath
+	t.mu.RUnlock()
+
+	w := newWalker(fsys)
+	fresh := unchanged(fsys, cached)
+	if fresh {
+		maps.Copy(w.assets, cached)
+	}
+	out, err := w.rewrite(name, page, true)
+	if err != nil {
+		return nil, err
+	}
+	if !fresh || len(w.assets) > len(cached) {
+		t.Update(w.result(out))
+	}
+	return out, nil
+}
+
+// unchanged reports whether the files of assets still have the size and
+// modification time they were read with. An empty table never is.
+func unchanged(fsys fs.FS, assets map[string]*Asset) bool {
+	if len(assets) == 0 {
+		return false
+	}
+	for _, a := range assets {
+		fi, err := fs.Stat(fsys, a.Path)
+		if err != nil || fi.Size() != a.size || !fi.ModTime().Equal(a.modTime) {
+			return false
+		}
+	}
+	return true
+}
+
+// Current returns the asset currently served for an original path.
+func (t *Table) Current(path string) (*Asset, bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	a, ok := t.byPath[path]
+	return a, ok
+}
+
+// Lookup returns the content and hex SHA-256 of a fingerprinted name.
+func (t *Table) Lookup(name string) (data []byte, sum string, ok bool) {
+	t.mu.RLock()
+	a, ok := t.byName[name]
+	t.mu.RUnlock()
+	if !ok {
+		return nil, "", false
+	}
+	return a.Data, a.Sum, true
+}
+
+// Gzipped returns the gzip-compressed content of a fingerprinted name, or
+// nil if there is none.
+func (t *Table) Gzipped(name string) []byte {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.gzip[name]
+}
@@ -0,0 +1,197 @@
+// Package flowgraph works on the graph a flow signal describes: it checks
+// it beyond the per-field rules in package signals and lays it out.
+package flowgraph
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/yacobolo/datastar-lit-examples/internal/signals"
+)
+
+// Check reports what is wrong with the graph in f, errors before warnings
+// and otherwise in the order of f's nodes and edges. The result is never
+// nil, so patching it as flow.errors clears issues that have been fixed
+// rather than deleting the signal.
+//
+// Errors are what <flow-diagram> draws wrongly or not at all: edges whose
+// ends are missing, ambiguous IDs, and edge IDs that are not integers (the
+// component derives each edge's animation phase with parseInt). Self-loops,
+// cycles and nodes without edges are reported as warnings.
+func Check(f signals.Flow) []signals.FlowIssue {
+	issues := []signals.FlowIssue{}
+	add := func(code signals.FlowIssueCode, sev signals.Severity, nodes, edges []string, format string, args ...any) {
+		issues = append(issues, signals.FlowIssue{
+			Code:     code,
+			Severity: sev,
+			Message:  fmt.Sprintf(format, args...),
+			Nodes:    nodes,
+			Edges:    edges,
+		})
+	}
+
+	nodes := map[string]bool{}
+	for _, id := range duplicates(len(f.Nodes), func(i int) string { return f.Nodes[i].ID }) {
+		add(signals.IssueDuplicateNodeID, signals.SeverityError, []string{id}, nil,
+			"node ID %q is used more than once", id)
+	}
+	for _, n := range f.Nodes {
+		nodes[n.ID] = true
+	}
+
+	for _, id := range duplicates(len(f.Edges), func(i int) string { return f.Edges[i].ID }) {
+		add(signals.IssueDuplicateEdgeID, signals.SeverityError, nil, []string{id},
+			"edge ID %q is used more than once", id)
+	}
+	for _, e := range f.Edges {
+		if _, err := strconv.Atoi(e.ID); err != nil {
+			add(signals.IssueEdgeID, signals.SeverityError, nil, []string{e.ID},
+				"edge ID %q is not an integer", e.ID)
+		}
+	}
+
+	// Only edges between existing nodes take part in the structural checks.
+	var edges []signals.FlowEdge
+	for _, e := range f.Edges {
+		var missing []string
+		for _, end := range []string{e.Source, e.Target} {
+			if !nodes[end] && !slices.Contains(missing, end) {
+				missing = append(missing, end)
+			}
+		}
+		if len(missing) > 0 {
+			add(signals.IssueDanglingEdge, signals.SeverityError, missing, []string{e.ID},
+				"edge %q points to missing node %s", e.ID, quoteList(missing))
+			continue
+		}
+		edges = append(edges, e)
+	}
+
+	for _, e := range edges {
+		if e.Source == e.Target {
+			add(signals.IssueSelfLoop, signals.SeverityWarning, []string{e.Source}, []string{e.ID},
+				"edge %q connects node %q to itself", e.ID, e.Source)
+		}
+	}
+	for _, cycle := range cycles(f.Nodes, edges) {
+		add(signals.IssueCycle, signals.SeverityWarning, cycle, nil,
+			"nodes %s form a cycle", quoteList(cycle))
+	}
+
+	if len(f.Nodes) > 1 {
+		linked := map[string]bool{}
+		for _, e := range edges {
+			linked[e.Source], linked[e.Target] = true, true
+		}
+		seen := map[string]bool{}
+		for _, n := range f.Nodes {
+			if !linked[n.ID] && !seen[n.ID] {
+				seen[n.ID] = true
+				add(signals.IssueOrphan, signals.SeverityWarning, []string{n.ID}, nil,
+					"node %q has no edges", n.ID)
+			}
+		}
+	}
+
+	slices.SortStableFunc(issues, func(a, b signals.FlowIssue) int {
+		return severityRank(a.Severity) - severityRank(b.Severity)
+	})
+	return issues
+}
+
+func severityRank(s signals.Severity) int {
+	if s == signals.SeverityError {
+		return 0
+	}
+	return 1
+}
+
+// duplicates returns the IDs that occur more than once among n items, in
+// the order of their second occurrence.
+func duplicates(n int, id func(int) string) []string {
+	count := map[string]int{}
+	var dups []string
+	for i := range n {
+		v := id(i)
+		count[v]++
+		if count[v] == 2 {
+			dups = append(dups, v)
+		}
+	}
+	return dups
+}
+
+// cycles returns the nodes of every cycle longer than one node, as the
+// strongly connected components of the graph (Tarjan's algorithm). Each
+// component lists its nodes in the order they appear in nodes.
+func cycles(nodes []signals.FlowNode, edges []signals.FlowEdge) [][]string {
+	order := map[string]int{}
+	for i, n := range nodes {
+		if _, ok := order[n.ID]; !ok {
+			order[n.ID] = i
+		}
+	}
+	out := map[string][]string{}
+	for _, e := range edges {
+		if e.Source != e.Target {
+			out[e.Source] = append(out[e.Source], e.Target)
+		}
+	}
+
+	var (
+		index   = map[string]int{}
+		low     = map[string]int{}
+		onStack = map[string]bool{}
+		stack   []string
+		result  [][]string
+		visit   func(string)
+	)
+	visit = func(v string) {
+		index[v] = len(index)
+		low[v] = index[v]
+		stack = append(stack, v)
+		onStack[v] = true
+		for _, w := range out[v] {
+			if _, seen := index[w]; !seen {
+				visit(w)
+				low[v] = min(low[v], low[w])
+			} else if onStack[w] {
+				low[v] = min(low[v], index[w])
+			}
+		}
+		if low[v] != index[v] {
+			return
+		}
+		var scc []string
+		for {
+			w := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			onStack[w] = false
+			scc = append(scc, w)
+			if w == v {
+				break
+			}
+		}
+		if len(scc) > 1 {
+			slices.SortFunc(scc, func(a, b string) int { return order[a] - order[b] })
+			result = append(result, scc)
+		}
+	}
+	for _, n := range nodes {
+		if _, seen := index[n.ID]; !seen {
+			visit(n.ID)
+		}
+	}
+	slices.SortFunc(result, func(a, b []string) int { return order[a[0]] - order[b[0]] })
+	return result
+}
+
+func quoteList(ids []string) string {
+	quoted := make([]string, len(ids))
+	for i, id := range ids {
+		quoted[i] = strconv.Quote(id)
+	}
+	return strings.Join(quoted, ", ")
+}
@@ -0,0 +1,130 @@
+package flowgraph
+
+import (
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/yacobolo/datastar-lit-examples/internal/signals"
+)
+
+// flow builds a flow from node IDs and source-target pairs; edges are
+// numbered from 1.
+func flow(nodes []string, edges ...[2]string) signals.Flow {
+	f := signals.Flow{}
+	for _, id := range nodes {
+		f.Nodes = append(f.Nodes, signals.FlowNode{ID: id, Label: id})
+	}
+	for i, e := range edges {
+		f.Edges = append(f.Edges, signals.FlowEdge{ID: strconv.Itoa(i + 1), Source: e[0], Target: e[1]})
+	}
+	return f
+}
+
+// issue is the part of a FlowIssue the tests compare; messages are free
+// to change.
+type issue struct {
+	Code     signals.FlowIssueCode
+	Severity signals.Severity
+	Nodes    []string
+	Edges    []string
+}
+
+func TestCheck(t *testing.T) {
+	withEdgeID := func(f signals.Flow, i int, id string) signals.Flow {
+		f.Edges[i].ID = id
+		return f
+	}
+
+	tests := []struct {
+		name string
+		flow signals.Flow
+		want []issue
+	}{
+		{
+			name: "empty",
+			flow: signals.Flow{},
+		},
+		{
+			name: "single node",
+			flow: flow([]string{"a"}),
+		},
+		{
+			name: "chain",
+			flow: flow([]string{"a", "b", "c"}, [2]string{"a", "b"}, [2]string{"b", "c"}),
+		},
+		{
+			name: "duplicate node ID",
+			flow: flow([]string{"a", "b", "a"}, [2]string{"a", "b"}),
+			want: []issue{{signals.IssueDuplicateNodeID, signals.SeverityError, []string{"a"}, nil}},
+		},
+		{
+			name: "duplicate edge ID",
+			flow: withEdgeID(flow([]string{"a", "b", "c"}, [2]string{"a", "b"}, [2]string{"b", "c"}), 1, "1"),
+			want: []issue{{signals.IssueDuplicateEdgeID, signals.SeverityError, nil, []string{"1"}}},
+		},
+		{
+			name: "non-numeric edge ID",
+			flow: withEdgeID(flow([]string{"a", "b"}, [2]string{"a", "b"}), 0, "ab"),
+			want: []issue{{signals.IssueEdgeID, signals.SeverityError, nil, []string{"ab"}}},
+		},
+		{
+			name: "dangling edge",
+			flow: flow([]string{"a", "b"}, [2]string{"a", "b"}, [2]string{"b", "x"}, [2]string{"y", "y"}),
+			want: []issue{
+				{signals.IssueDanglingEdge, signals.SeverityError, []string{"x"}, []string{"2"}},
+				{signals.IssueDanglingEdge, signals.SeverityError, []string{"y"}, []string{"3"}},
+			},
+		},
+		{
+			name: "self-loop",
+			flow: flow([]string{"a", "b"}, [2]string{"a", "b"}, [2]string{"b", "b"}),
+			want: []issue{{signals.IssueSelfLoop, signals.SeverityWarning, []string{"b"}, []string{"2"}}},
+		},
+		{
+			name: "cycles",
+			flow: flow([]string{"a", "b", "c", "d", "e"},
+				[2]string{"c", "a"}, [2]string{"a", "b"}, [2]string{"b", "c"},
+				[2]string{"c", "d"}, [2]string{"d", "e"}, [2]string{"e", "d"}),
+			want: []issue{
+				{signals.IssueCycle, signals.SeverityWarning, []string{"a", "b", "c"}, nil},
+				{signals.IssueCycle, signals.SeverityWarning, []string{"d", "e"}, nil},
+			},
+		},
+		{
+			name: "orphans",
+			flow: flow([]string{"a", "b", "c", "d"}, [2]string{"b", "c"}, [2]string{"c", "x"}),
+			want: []issue{
+				{signals.IssueDanglingEdge, signals.SeverityError, []string{"x"}, []string{"2"}},
+				{signals.IssueOrphan, signals.SeverityWarning, []string{"a"}, nil},
+				{signals.IssueOrphan, signals.SeverityWarning, []string{"d"}, nil},
+			},
+		},
+		{
+			name: "errors before warnings",
+			flow: withEdgeID(flow([]string{"a", "b", "c"}, [2]string{"a", "a"}, [2]string{"b", "c"}), 1, "two"),
+			want: []issue{
+				{signals.IssueEdgeID, signals.SeverityError, nil, []string{"two"}},
+				{signals.IssueSelfLoop, signals.SeverityWarning, []string{"a"}, []string{"1"}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			issues := Check(tt.flow)
+			if issues == nil {
+				t.Fatal("Check returned nil, want an empty slice")
+			}
+			var got []issue
+			for _, i := range issues {
+				if i.Message == "" {
+					t.Errorf("%s issue has no message", i.Code)
+				}
+				got = append(got, issue{i.Code, i.Severity, i.Nodes, i.Edges})
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Check() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,442 @@
+package flowgraph
+
+import (
+	"fmt"
+	"math"
+	"slices"
+
+	"github.com/yacobolo/datastar-lit-examples/internal/signals"
+)
+
+// Algorithm selects a layout engine.
+type Algorithm string
+
+const (
+	// Layered ranks the graph left to right, Sugiyama style: cycles are
+	// broken, nodes are assigned to columns by longest path and each column
+	// is ordered to reduce edge crossings. It suits DAGs such as pipelines.
+	Layered Algorithm = "layered"
+	// Force simulates repelling nodes joined by springs (Fruchterman and
+	// Reingold), starting from the current positions. It suits graphs with
+	// cycles or no clear direction.
+	Force Algorithm = "force"
+	// Grid places the nodes in rows, in the order they are listed.
+	Grid Algorithm = "grid"
+)
+
+// Algorithms lists every Algorithm in the order the page offers them.
+var Algorithms = []Algorithm{Layered, Force, Grid}
+
+// Options describe the canvas a layout has to fit.
+type Options struct {
+	Width, Height float64
+	// NodeRadius is config.nodeRadius. Nodes are kept far enough apart that
+	// their glows, which reach 1.5 radii, do not overlap, and far enough
+	// from the edges that the glow is not clipped.
+	NodeRadius float64
+	// Vertical makes Layered rank top to bottom instead of left to right.
+	Vertical bool
+}
+
+// DefaultOptions is the canvas <flow-diagram> draws on at its default
+// height, and a typical width.
+var DefaultOptions = Options{Width: 480, Height: 300, NodeRadius: 30}
+
+func (o Options) margin() float64 { return 1.5*o.NodeRadius + 4 }
+func (o Options) gap() float64    { return 3 * o.NodeRadius }
+
+// ForceIterations is how many steps the force-directed layout simulates.
+const ForceIterations = 120
+
+// MaxNodes and MaxEdges bound the flows the server checks and lays out.
+// Larger ones take seconds and hundreds of megabytes, so callers reject
+// them first.
+const (
+	MaxNodes = 500
+	MaxEdges = 2000
+)
+
+// CheckSize returns an error if f has more than MaxNodes nodes or
+// MaxEdges edges.
+func CheckSize(f signals.Flow) error {
+	if len(f.Nodes) > MaxNodes {
+		return fmt.Errorf("flow has %d nodes, more than the %d allowed", len(f.Nodes), MaxNodes)
+	}
+	if len(f.Edges) > MaxEdges {
+		return fmt.Errorf("flow has %d edges, more than the %d allowed", len(f.Edges), MaxEdges)
+	}
+	return nil
+}
+
+// maxDummies caps the vertices Layered adds to split long edges. Edges
+// that would go past it are left out of the crossing reduction, which
+// only matters for graphs far bigger than fit on the canvas.
+const maxDummies = 20000
+
+// Layout returns f's nodes in their original order with positions computed
+// by algorithm. Everything but x and y is left as it was. For Force, step
+// is called with the positions after every iteration and the simulation
+// stops early if it returns false; the other engines ignore it.
+func Layout(algorithm Algorithm, f signals.Flow, o Options, step func([]signals.FlowNode) bool) []signals.FlowNode {
+	nodes := slices.Clone(f.Nodes)
+	if len(nodes) == 0 {
+		return nodes
+	}
+	g := newGraph(f)
+	var pos []point
+	switch algorithm {
+	case Force:
+		pos = g.force(nodes, o, step)
+	case Grid:
+		pos = grid(len(nodes), o)
+	default:
+		if o.Vertical {
+			o.Width, o.Height = o.Height, o.Width
+		}
+		pos = g.layered(o)
+		if o.Vertical {
+			for i := range pos {
+				pos[i].x, pos[i].y = pos[i].y, pos[i].x
+			}
+		}
+	}
+	for i := range nodes {
+		nodes[i].X, nodes[i].Y = math.Round(pos[i].x), math.Round(pos[i].y)
+	}
+	return nodes
+}
+
+// Tween returns the nodes of to with positions part of the way from their
+// positions in from, eased in and out; t runs from 0 to 1. Both must list
+// the same nodes in the same order.
+func Tween(from, to []signals.FlowNode, t float64) []signals.FlowNode {
+	t = max(0, min(1, t))
+	e := t * t * (3 - 2*t)
+	out := slices.Clone(to)
+	for i := range out {
+		if i < len(from) {
+			out[i].X = math.Round(from[i].X + (to[i].X-from[i].X)*e)
+			out[i].Y = math.Round(from[i].Y + (to[i].Y-from[i].Y)*e)
+		}
+	}
+	return out
+}
+
+type point struct{ x, y float64 }
+
+// graph is f's edges as adjacency by node index, without self-loops,
+// duplicates and edges to missing nodes. A node ID used twice refers to
+// its first occurrence.
+type graph struct {
+	n        int
+	out      [][]int
+	edgeList [][2]int
+}
+
+func newGraph(f signals.Flow) *graph {
+	g := &graph{n: len(f.Nodes), out: make([][]int, len(f.Nodes))}
+	index := map[string]int{}
+	for i, n := range f.Nodes {
+		if _, ok := index[n.ID]; !ok {
+			index[n.ID] = i
+		}
+	}
+	seen := map[[2]int]bool{}
+	for _, e := range f.Edges {
+		s, ok1 := index[e.Source]
+		t, ok2 := index[e.Target]
+		if !ok1 || !ok2 || s == t || seen[[2]int{s, t}] {
+			continue
+		}
+		seen[[2]int{s, t}] = true
+		g.out[s] = append(g.out[s], t)
+		g.edgeList = append(g.edgeList, [2]int{s, t})
+	}
+	return g
+}
+
+// layered is the Sugiyama pipeline: acyclic orientation, longest-path
+// ranking, dummy nodes for edges that span several ranks, barycentric
+// crossing reduction, then even spacing within the canvas.
+func (g *graph) layered(o Options) []point {
+	// Reverse the edges that close a cycle in a depth-first search, so the
+	// remaining graph is acyclic.
+	state := make([]int, g.n) // 0 unvisited, 1 on the stack, 2 done
+	var edges [][2]int
+	var visit func(int)
+	visit = func(v int) {
+		state[v] = 1
+		for _, w := range g.out[v] {
+			switch state[w] {
+			case 0:
+				edges = append(edges, [2]int{v, w})
+				visit(w)
+			case 1:
+				edges = append(edges, [2]int{w, v})
+			default:
+				edges = append(edges, [2]int{v, w})
+			}
+		}
+		state[v] = 2
+	}
+	for v := range g.n {
+		if state[v] == 0 {
+			visit(v)
+		}
+	}
+
+	// Longest path from the sources, in topological order.
+	rank := make([]int, g.n)
+	indeg := make([]int, g.n)
+	succ := make([][]int, g.n)
+	for _, e := range edges {
+		succ[e[0]] = append(succ[e[0]], e[1])
+		indeg[e[1]]++
+	}
+	var queue []int
+	for v := range g.n {
+		if indeg[v] == 0 {
+			queue = append(queue, v)
+		}
+	}
+	for len(queue) > 0 {
+		v := queue[0]
+		queue = queue[1:]
+		for _, w := range succ[v] {
+			rank[w] = max(rank[w], rank[v]+1)
+			if indeg[w]--; indeg[w] == 0 {
+				queue = append(queue, w)
+			}
+		}
+	}
+
+	// Split long edges with dummy vertices so every edge joins adjacent
+	// ranks. Vertices past g.n are dummies.
+	vrank := slices.Clone(rank)
+	up := make([][]int, g.n)   // neighbors one rank to the left
+	down := make([][]int, g.n) // neighbors one rank to the right
+	for _, e := range edges {
+		if span := rank[e[1]] - rank[e[0]] - 1; len(vrank)-g.n+span > maxDummies {
+			continue
+		}
+		prev := e[0]
+		for r := rank[e[0]] + 1; r < rank[e[1]]; r++ {
+			d := len(vrank)
+			vrank = append(vrank, r)
+			up, down = append(up, nil), append(down, nil)
+			down[prev] = append(down[prev], d)
+			up[d] = append(up[d], prev)
+			prev = d
+		}
+		down[prev] = append(down[prev], e[1])
+		up[e[1]] = append(up[e[1]], prev)
+	}
+
+	ranks := slices.Max(vrank) + 1
+	layers := make([][]int, ranks)
+	for v, r := range vrank {
+		layers[r] = append(layers[r], v)
+	}
+
+	// Order each rank by the mean position of its neighbors in the rank
+	// before it, sweeping right and then left; vertices without neighbors
+	// there keep their place.
+	order := make([]float64, len(vrank))
+	renumber := func(layer []int) {
+		for i, v := range layer {
+			order[v] = float64(i)
+		}
+	}
+	for _, layer := range layers {
+		renumber(layer)
+	}
+	// bary is shared by all sweeps; each only touches its own layer.
+	bary := make([]float64, len(vrank))
+	sweep := func(r int, adj [][]int) {
+		layer := layers[r]
+		for _, v := range layer {
+			bary[v] = order[v]
+			if len(adj[v]) > 0 {
+				var sum float64
+				for _, w := range adj[v] {
+					sum += order[w]
+				}
+				bary[v] = sum / float64(len(adj[v]))
+			}
+		}
+		slices.SortStableFunc(layer, func(a, b int) int {
+			switch {
+			case bary[a] < bary[b]:
+				return -1
+			case bary[a] > bary[b]:
+				return 1
+			}
+			return 0
+		})
+		renumber(layer)
+	}
+	for range 4 {
+		for r := 1; r < ranks; r++ {
+			sweep(r, up)
+		}
+		for r := ranks - 2; r >= 0; r-- {
+			sweep(r, down)
+		}
+	}
+
+	// Ranks become columns spread over the width, and each column is
+	// centered vertically.
+	m, gap := o.margin(), o.gap()
+	dx := 0.0
+	if ranks > 1 {
+		dx = max(gap, (o.Width-2*m)/float64(ranks-1))
+	}
+	x0 := o.Width/2 - dx*float64(ranks-1)/2
+	pos := make([]point, g.n)
+	for r, layer := range layers {
+		dy := 0.0
+		if len(layer) > 1 {
+			dy = max(gap, min((o.Height-2*m)/float64(len(layer)-1), 2*gap))
+		}
+		y0 := o.Height/2 - dy*float64(len(layer)-1)/2
+		for i, v := range layer {
+			if v < g.n {
+				pos[v] = point{x0 + dx*float64(r), y0 + dy*float64(i)}
+			}
+		}
+	}
+	return pos
+}
+
+// force runs the Fruchterman-Reingold simulation with gravity toward the
+// center, so disconnected parts stay on the canvas.
+func (g *graph) force(nodes []signals.FlowNode, o Options, step func([]signals.FlowNode) bool) []point {
+	n := len(nodes)
+	m, gap := o.margin(), o.gap()
+	pos := make([]point, n)
+	for i, node := range nodes {
+		pos[i] = point{node.X, node.Y}
+	}
+	// Nodes on the same spot would never separate; nudge them apart along
+	// a spiral so the result does not depend on chance.
+	for i := range pos {
+		for j := range i {
+			if pos[i] == pos[j] {
+				a := float64(i) * 2.399963 // golden angle
+				pos[i].x += math.Cos(a) * gap / 2
+				pos[i].y += math.Sin(a) * gap / 2
+			}
+		}
+	}
+
+	k := max(gap, 0.75*math.Sqrt((o.Width-2*m)*(o.Height-2*m)/float64(n)))
+	center := point{o.Width / 2, o.Height / 2}
+	t0 := o.Width / 10
+	disp := make([]point, n)
+	frame := slices.Clone(nodes)
+	for it := range ForceIterations {
+		temp := t0 * (1 - float64(it)/ForceIterations)
+		clear(disp)
+		for i := range n {
+			for j := i + 1; j < n; j++ {
+				dx, dy, d := delta(pos[i], pos[j])
+				f := k * k / d
+				disp[i].x += dx / d * f
+				disp[i].y += dy / d * f
+				disp[j].x -= dx / d * f
+				disp[j].y -= dy / d * f
+			}
+		}
+		for _, e := range g.edgeList {
+			dx, dy, d := delta(pos[e[0]], pos[e[1]])
+			f := d * d / k
+			disp[e[0]].x -= dx / d * f
+			disp[e[0]].y -= dy / d * f
+			disp[e[1]].x += dx / d * f
+			disp[e[1]].y += dy / d * f
+		}
+		for i := range n {
+			dx, dy, d := delta(pos[i], center)
+			f := 0.3 * d * d / k
+			disp[i].x -= dx / d * f
+			disp[i].y -= dy / d * f
+
+			_, _, l := delta(disp[i], point{})
+			move := min(l, temp)
+			pos[i].x = clamp(pos[i].x+disp[i].x/l*move, m, o.Width-m)
+			pos[i].y = clamp(pos[i].y+disp[i].y/l*move, m, o.Height-m)
+		}
+
+		if step != nil {
+			for i := range frame {
+				frame[i].X, frame[i].Y = math.Round(pos[i].x), math.Round(pos[i].y)
+			}
+			if !step(slices.Clone(frame)) {
+				break
+			}
+		}
+	}
+
+	// Springs can leave neighbors closer than their glows allow; push such
+	// pairs apart.
+	for range 50 {
+		moved := false
+		for i := range n {
+			for j := i + 1; j < n; j++ {
+				dx, dy, d := delta(pos[i], pos[j])
+				if d >= gap {
+					continue
+				}
+				push := (gap - d) / 2
+				pos[i].x = clamp(pos[i].x+dx/d*push, m, o.Width-m)
+				pos[i].y = clamp(pos[i].y+dy/d*push, m, o.Height-m)
+				pos[j].x = clamp(pos[j].x-dx/d*push, m, o.Width-m)
+				pos[j].y = clamp(pos[j].y-dy/d*push, m, o.Height-m)
+				moved = true
+			}
+		}
+		if !moved {
+			break
+		}
+	}
+	return pos
+}
+
+// grid fills rows left to right, with about as many columns per row as the
+// canvas's aspect ratio suggests.
+func grid(n int, o Options) []point {
+	m, gap := o.margin(), o.gap()
+	w, h := max(o.Width-2*m, 0), max(o.Height-2*m, 0)
+	cols := int(math.Ceil(math.Sqrt(float64(n) * max(w, 1) / max(h, 1))))
+	cols = max(1, min(cols, n))
+	rows := (n + cols - 1) / cols
+
+	dx, dy := 0.0, 0.0
+	if cols > 1 {
+		dx = max(gap, w/float64(cols-1))
+	}
+	if rows > 1 {
+		dy = max(gap, h/float64(rows-1))
+	}
+	x0 := o.Width/2 - dx*float64(cols-1)/2
+	y0 := o.Height/2 - dy*float64(rows-1)/2
+	pos := make([]point, n)
+	for i := range pos {
+		pos[i] = point{x0 + dx*float64(i%cols), y0 + dy*float64(i/cols)}
+	}
+	return pos
+}
+
+// delta returns the vector from b to a and its length, which is never
+// zero so it can be divided by.
+func delta(a, b point) (dx, dy, d float64) {
+	dx, dy = a.x-b.x, a.y-b.y
+	return dx, dy, max(math.Hypot(dx, dy), 0.01)
+}
+
+func clamp(v, lo, hi float64) float64 {
+	if hi < lo {
+		return (lo + hi) / 2
+	}
+	return max(lo, min(v, hi))
+}
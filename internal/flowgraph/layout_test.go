@@ -0,0 +1,145 @@
+package flowgraph
+
+import (
+	"reflect"
+	"slices"
+	"strconv"
+	"testing"
+
+	"github.com/yacobolo/datastar-lit-examples/internal/signals"
+)
+
+// positioned gives the nodes of f distinct starting positions, as a flow
+// on the page has.
+func positioned(f signals.Flow) signals.Flow {
+	for i := range f.Nodes {
+		f.Nodes[i].X, f.Nodes[i].Y = float64(40+37*i%400), float64(40+53*i%220)
+	}
+	return f
+}
+
+func TestLayered(t *testing.T) {
+	tests := []struct {
+		name     string
+		flow     signals.Flow
+		vertical bool
+	}{
+		{
+			name: "chain",
+			flow: flow([]string{"c", "b", "a"}, [2]string{"a", "b"}, [2]string{"b", "c"}),
+		},
+		{
+			name: "diamond",
+			flow: flow([]string{"a", "b", "c", "d"},
+				[2]string{"a", "b"}, [2]string{"a", "c"}, [2]string{"b", "d"}, [2]string{"c", "d"}),
+		},
+		{
+			name: "long edge",
+			flow: flow([]string{"a", "b", "c", "d"},
+				[2]string{"a", "b"}, [2]string{"b", "c"}, [2]string{"c", "d"}, [2]string{"a", "d"}),
+		},
+		{
+			name: "two sources",
+			flow: flow([]string{"x", "a", "y", "b"},
+				[2]string{"a", "b"}, [2]string{"x", "y"}, [2]string{"y", "b"}),
+		},
+		{
+			name:     "vertical",
+			flow:     flow([]string{"a", "b", "c", "d"}, [2]string{"a", "b"}, [2]string{"a", "c"}, [2]string{"c", "d"}),
+			vertical: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := DefaultOptions
+			o.Vertical = tt.vertical
+			nodes := Layout(Layered, tt.flow, o, nil)
+			at := map[string]signals.FlowNode{}
+			for _, n := range nodes {
+				at[n.ID] = n
+			}
+			for _, e := range tt.flow.Edges {
+				s, d := at[e.Source], at[e.Target]
+				if tt.vertical && s.Y >= d.Y || !tt.vertical && s.X >= d.X {
+					t.Errorf("edge %s->%s runs from (%v,%v) to (%v,%v), against the ranks",
+						e.Source, e.Target, s.X, s.Y, d.X, d.Y)
+				}
+			}
+		})
+	}
+}
+
+func TestForce(t *testing.T) {
+	tests := []struct {
+		name string
+		flow signals.Flow
+	}{
+		{
+			name: "cycle",
+			flow: positioned(flow([]string{"a", "b", "c"}, [2]string{"a", "b"}, [2]string{"b", "c"}, [2]string{"c", "a"})),
+		},
+		{
+			name: "disconnected",
+			flow: positioned(flow([]string{"a", "b", "c", "d", "e"}, [2]string{"a", "b"}, [2]string{"d", "e"})),
+		},
+		{
+			// All at the origin, which the layout has to spread out.
+			name: "stacked",
+			flow: flow([]string{"a", "b", "c", "d"}, [2]string{"a", "b"}, [2]string{"c", "d"}),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := tt.flow
+			before := slices.Clone(f.Nodes)
+			first := Layout(Force, f, DefaultOptions, nil)
+			if !reflect.DeepEqual(f.Nodes, before) {
+				t.Errorf("Layout changed its input to %+v", f.Nodes)
+			}
+			var steps [][]signals.FlowNode
+			second := Layout(Force, f, DefaultOptions, func(nodes []signals.FlowNode) bool {
+				steps = append(steps, nodes)
+				return true
+			})
+			if !reflect.DeepEqual(first, second) {
+				t.Errorf("Layout gave %+v, then %+v from the same positions", first, second)
+			}
+			if len(steps) != ForceIterations {
+				t.Errorf("step called %d times, want %d", len(steps), ForceIterations)
+			}
+			m := DefaultOptions.margin()
+			for _, n := range first {
+				if n.X < m-1 || n.X > DefaultOptions.Width-m+1 || n.Y < m-1 || n.Y > DefaultOptions.Height-m+1 {
+					t.Errorf("node %s at (%v,%v) is off the canvas", n.ID, n.X, n.Y)
+				}
+			}
+		})
+	}
+}
+
+func TestCheckSize(t *testing.T) {
+	sized := func(nodes, edges int) signals.Flow {
+		f := signals.Flow{Nodes: make([]signals.FlowNode, nodes), Edges: make([]signals.FlowEdge, edges)}
+		for i := range f.Nodes {
+			f.Nodes[i].ID = "n" + strconv.Itoa(i)
+		}
+		return f
+	}
+	tests := []struct {
+		name    string
+		flow    signals.Flow
+		wantErr bool
+	}{
+		{"empty", signals.Flow{}, false},
+		{"at the limits", sized(MaxNodes, MaxEdges), false},
+		{"too many nodes", sized(MaxNodes+1, 0), true},
+		{"too many edges", sized(2, MaxEdges+1), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := CheckSize(tt.flow); (err != nil) != tt.wantErr {
+				t.Errorf("CheckSize() = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,245 @@
+// Package flowstore keeps flow diagram documents on disk, one JSON file per
+// document, so diagrams survive a reload without a database. Every write
+// goes to a temporary file that is renamed over the old one, so a crash
+// leaves either the previous or the new document, never half of one.
+package flowstore
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/yacobolo/datastar-lit-examples/internal/signals"
+)
+
+// MaxNameLen is the longest document name kept; longer names are cut.
+const MaxNameLen = 80
+
+// ErrNotFound is returned for an ID that has no document.
+var ErrNotFound = errors.New("flowstore: document not found")
+
+var validID = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// Document is one saved diagram.
+type Document struct {
+	ID      string       `json:"id"`
+	Name    string       `json:"name"`
+	Created time.Time    `json:"created"`
+	Updated time.Time    `json:"updated"`
+	Flow    signals.Flow `json:"flow"`
+}
+
+// Info describes a document without its diagram, for listings.
+type Info struct {
+	ID      string    `json:"id"`
+	Name    string    `json:"name"`
+	Updated time.Time `json:"updated"`
+	Nodes   int       `json:"nodes"`
+	Edges   int       `json:"edges"`
+}
+
+// Store is a directory of documents named <id>.json. It is safe for
+// concurrent use within one process.
+type Store struct {
+	dir string
+	mu  sync.Mutex // serializes writes, so Save keeps Created intact
+}
+
+// Open returns the store in dir, creating the directory if needed.
+func Open(dir string) (*Store, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("flowstore: %w", err)
+	}
+	return &Store{dir: dir}, nil
+}
+
+// Check reports whether the store's directory can be listed and written.
+func (s *Store) Check() error {
+	if _, err := os.ReadDir(s.dir); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(s.dir, ".check-*")
+	if err != nil {
+		return err
+	}
+	f.Close()
+	return os.Remove(f.Name())
+}
+
+// List returns every document, most recently updated first. Files that
+// cannot be read or decoded are skipped rather than failing the listing.
+func (s *Store) List() ([]Info, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, fmt.Errorf("flowstore: %w", err)
+	}
+	var infos []Info
+	for _, e := range entries {
+		id, ok := strings.CutSuffix(e.Name(), ".json")
+		if !ok || !e.Type().IsRegular() || !validID.MatchString(id) {
+			continue
+		}
+		doc, err := s.Get(id)
+		if err != nil {
+			continue
+		}
+		infos = append(infos, Info{
+			ID:      doc.ID,
+			Name:    doc.Name,
+			Updated: doc.Updated,
+			Nodes:   len(doc.Flow.Nodes),
+			Edges:   len(doc.Flow.Edges),
+		})
+	}
+	slices.SortFunc(infos, func(a, b Info) int {
+		if c := b.Updated.Compare(a.Updated); c != 0 {
+			return c
+		}
+		return strings.Compare(a.ID, b.ID)
+	})
+	return infos, nil
+}
+
+// Get reads the document with the given ID.
+func (s *Store) Get(id string) (Document, error) {
+	path, err := s.path(id)
+	if err != nil {
+		return Document{}, err
+	}
+	b, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return Document{}, fmt.Errorf("%w: %s", ErrNotFound, id)
+	} else if err != nil {
+		return Document{}, fmt.Errorf("flowstore: %w", err)
+	}
+	var doc Document
+	if err := json.Unmarshal(b, &doc); err != nil {
+		return Document{}, fmt.Errorf("flowstore: %s: %w", id, err)
+	}
+	doc.ID = id
+	return doc, nil
+}
+
+// Create stores flow as a new document under a fresh ID.
+func (s *Store) Create(name string, flow signals.Flow) (Document, error) {
+	if err := flow.Validate(); err != nil {
+		return Document{}, err
+	}
+	id, err := newID()
+	if err != nil {
+		return Document{}, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	flow.Errors = nil // derived, so not worth keeping
+	now := time.Now().UTC()
+	doc := Document{ID: id, Name: cleanName(name), Created: now, Updated: now, Flow: flow}
+	return doc, s.write(doc)
+}
+
+// Save replaces the diagram and name of an existing document.
+func (s *Store) Save(id, name string, flow signals.Flow) (Document, error) {
+	if err := flow.Validate(); err != nil {
+		return Document{}, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	doc, err := s.Get(id)
+	if err != nil {
+		return Document{}, err
+	}
+	doc.Name = cleanName(name)
+	doc.Updated = time.Now().UTC()
+	doc.Flow = flow
+	doc.Flow.Errors = nil
+	return doc, s.write(doc)
+}
+
+// Delete removes the document with the given ID.
+func (s *Store) Delete(id string) error {
+	path, err := s.path(id)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := os.Remove(path); errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("%w: %s", ErrNotFound, id)
+	} else if err != nil {
+		return fmt.Errorf("flowstore: %w", err)
+	}
+	return nil
+}
+
+// write saves doc atomically: the JSON goes to a temporary file in the same
+// directory, is synced, and then renamed over <id>.json.
+func (s *Store) write(doc Document) error {
+	path, err := s.path(doc.ID)
+	if err != nil {
+		return err
+	}
+	b, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return fmt.Errorf("flowstore: %w", err)
+	}
+	f, err := os.CreateTemp(s.dir, "."+doc.ID+"-*.tmp")
+	if err != nil {
+		return fmt.Errorf("flowstore: %w", err)
+	}
+	tmp := f.Name()
+	_, err = f.Write(append(b, '\n'))
+	if err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Chmod(tmp, 0o644)
+	}
+	if err == nil {
+		err = os.Rename(tmp, path)
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("flowstore: save %s: %w", doc.ID, err)
+	}
+	return nil
+}
+
+func (s *Store) path(id string) (string, error) {
+	if !validID.MatchString(id) {
+		return "", fmt.Errorf("%w: invalid id %q", ErrNotFound, id)
+	}
+	return filepath.Join(s.dir, id+".json"), nil
+}
+
+// cleanName trims name to one line of at most MaxNameLen characters.
+func cleanName(name string) string {
+	name = strings.Join(strings.Fields(name), " ")
+	if r := []rune(name); len(r) > MaxNameLen {
+		name = string(r[:MaxNameLen])
+	}
+	if name == "" {
+		name = "Untitled"
+	}
+	return name
+}
+
+func newID() (string, error) {
+	b := make([]byte, 6)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("flowstore: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,154 @@
+package flowstore
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/yacobolo/datastar-lit-examples/internal/signals"
+)
+
+func open(t *testing.T) *Store {
+	t.Helper()
+	s, err := Open(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestLifecycle(t *testing.T) {
+	s := open(t)
+	flow := signals.Default().Flow
+	flow.Errors = []signals.FlowIssue{{Code: signals.IssueOrphan}}
+
+	doc, err := s.Create("Pipeline", flow)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !validID.MatchString(doc.ID) {
+		t.Errorf("Create gave ID %q", doc.ID)
+	}
+	got, err := s.Get(doc.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Name != "Pipeline" || len(got.Flow.Nodes) != len(flow.Nodes) || len(got.Flow.Edges) != len(flow.Edges) {
+		t.Errorf("Get = %q with %d nodes and %d edges, want %q with %d and %d",
+			got.Name, len(got.Flow.Nodes), len(got.Flow.Edges), "Pipeline", len(flow.Nodes), len(flow.Edges))
+	}
+	if got.Flow.Errors != nil {
+		t.Errorf("stored flow kept its errors: %v", got.Flow.Errors)
+	}
+
+	flow.Nodes = flow.Nodes[:1]
+	flow.Edges = nil
+	saved, err := s.Save(doc.ID, "Renamed", flow)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !saved.Created.Equal(doc.Created) || saved.Updated.Before(doc.Updated) {
+		t.Errorf("Save changed Created or moved Updated back: %v, %v", saved.Created, saved.Updated)
+	}
+	infos, err := s.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(infos) != 1 || infos[0].ID != doc.ID || infos[0].Name != "Renamed" || infos[0].Nodes != 1 || infos[0].Edges != 0 {
+		t.Errorf("List = %+v", infos)
+	}
+
+	if err := s.Delete(doc.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Get(doc.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get after Delete: %v, want ErrNotFound", err)
+	}
+	if infos, err := s.List(); err != nil || len(infos) != 0 {
+		t.Errorf("List after Delete = %v, %v", infos, err)
+	}
+}
+
+func TestNotFound(t *testing.T) {
+	s := open(t)
+	flow := signals.Default().Flow
+	for _, id := range []string{"0123456789ab", "", "../secret", "a/b", "a.json"} {
+		if _, err := s.Get(id); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Get(%q): %v, want ErrNotFound", id, err)
+		}
+		if _, err := s.Save(id, "x", flow); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Save(%q): %v, want ErrNotFound", id, err)
+		}
+		if err := s.Delete(id); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Delete(%q): %v, want ErrNotFound", id, err)
+		}
+	}
+}
+
+func TestInvalidFlow(t *testing.T) {
+	s := open(t)
+	flow := signals.Default().Flow
+	flow.Config.NodeRadius = 0
+
+	_, err := s.Create("Bad", flow)
+	var verr signals.ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("Create: %v, want a ValidationError", err)
+	}
+	if infos, _ := s.List(); len(infos) != 0 {
+		t.Errorf("invalid flow was stored: %+v", infos)
+	}
+}
+
+func TestNames(t *testing.T) {
+	tests := []struct {
+		name, want string
+	}{
+		{"Pipeline", "Pipeline"},
+		{"  two\n lines\t", "two lines"},
+		{"", "Untitled"},
+		{" \n ", "Untitled"},
+		{strings.Repeat("é", MaxNameLen+5), strings.Repeat("é", MaxNameLen)},
+	}
+	s := open(t)
+	for _, tt := range tests {
+		doc, err := s.Create(tt.name, signals.Default().Flow)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, _ := s.Get(doc.ID); got.Name != tt.want {
+			t.Errorf("name %q stored as %q, want %q", tt.name, got.Name, tt.want)
+		}
+	}
+}
+
+func TestNoTempFiles(t *testing.T) {
+	s := open(t)
+	doc, err := s.Create("Pipeline", signals.Default().Flow)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for range 3 {
+		if _, err := s.Save(doc.ID, "Pipeline", signals.Default().Flow); err != nil {
+			t.Fatal(err)
+		}
+	}
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Name() != doc.ID+".json" {
+		var names []string
+		for _, e := range entries {
+			names = append(names, e.Name())
+		}
+		t.Errorf("store holds %v, want only %s.json", names, doc.ID)
+	}
+	if err := s.Check(); err != nil {
+		t.Errorf("Check: %v", err)
+	}
+	if entries, _ := os.ReadDir(s.dir); len(entries) != 1 {
+		t.Errorf("Check left %d files behind", len(entries)-1)
+	}
+}
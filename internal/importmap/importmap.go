@@ -0,0 +1,433 @@
+// Package importmap vendors the CDN dependencies of index.html, the
+// importmap entries and remote stylesheets, so the page works without
+// network access. Each URL is resolved from node_modules or, failing that,
+// from a directory mirroring the CDN, bundled with esbuild into one vendor
+// directory, and recorded in a manifest the server rewrites the page with.
+package importmap
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/evanw/esbuild/pkg/api"
+
+	"github.com/yacobolo/datastar-lit-examples/internal/assets"
+)
+
+// Dir is where vendored files go, relative to the site root. It is inside
+// demo/dist so the default allowlist and the embed build already cover it.
+const Dir = "demo/dist/vendor"
+
+// ManifestFile is the manifest's path relative to the site root.
+const ManifestFile = Dir + "/manifest.json"
+
+// Page is what index.html loads from other origins.
+type Page struct {
+	Imports     map[string]string // importmap specifier -> URL
+	Stylesheets []string          // remote <link rel="stylesheet"> hrefs
+}
+
+var (
+	importmapScript = regexp.MustCompile(`(?s)<script type="importmap">(.*?)</script>`)
+	stylesheetLink  = regexp.MustCompile(`<link rel="stylesheet" href="(https?://[^"]+)">`)
+)
+
+// Parse finds the importmap and remote stylesheets of an HTML page.
+func Parse(html []byte) (Page, error) {
+	var p Page
+	m := importmapScript.FindSubmatch(html)
+	if m == nil {
+		return p, errors.New(`importmap: no <script type="importmap"> in page`)
+	}
+	var im struct {
+		Imports map[string]string `json:"imports"`
+	}
+	if err := json.Unmarshal(m[1], &im); err != nil {
+		return p, fmt.Errorf("importmap: %w", err)
+	}
+	p.Imports = im.Imports
+	for _, m := range stylesheetLink.FindAllSubmatch(html, -1) {
+		p.Stylesheets = append(p.Stylesheets, string(m[1]))
+	}
+	return p, nil
+}
+
+// Options configure Vendor.
+type Options struct {
+	// Dir is the site root. node_modules is looked up from here and the
+	// output goes to Dir/demo/dist/vendor.
+	Dir string
+	// Cache is an optional directory mirroring the CDNs as <host>/<path>,
+	// the layout wget --force-directories produces.
+	Cache  string
+	Minify bool
+}
+
+// Entry is one vendored URL.
+type Entry struct {
+	URL  string
+	File string // relative to the site root
+	From string // the package specifier or cached file it was built from
+}
+
+// Vendor resolves and bundles every URL of p, replaces the vendor
+// directory with the result and writes the manifest. Nothing is written
+// unless every URL resolves.
+func Vendor(p Page, o Options) ([]Entry, error) {
+	dir, err := filepath.Abs(cmp.Or(o.Dir, "."))
+	if err != nil {
+		return nil, err
+	}
+	if o.Cache != "" {
+		if o.Cache, err = filepath.Abs(o.Cache); err != nil {
+			return nil, err
+		}
+	}
+
+	var reqs []*request
+	for _, key := range slices.Sorted(maps.Keys(p.Imports)) {
+		u := p.Imports[key]
+		_, specs := packageSpecs(dir, u)
+		reqs = append(reqs, &request{url: u, name: outputName(key), specs: append(specs, key)})
+	}
+	for i, u := range p.Stylesheets {
+		pkg, specs := packageSpecs(dir, u)
+		name := fmt.Sprintf("style-%d", i+1)
+		if pkg != "" {
+			name = outputName(pkg)
+		}
+		reqs = append(reqs, &request{url: u, name: name, specs: specs, css: true})
+	}
+
+	if err := resolveModules(dir, reqs); err != nil {
+		return nil, err
+	}
+	var missing []string
+	for _, r := range reqs {
+		if r.input != "" {
+			continue
+		}
+		if file, err := cacheFile(o.Cache, r.url); err == nil {
+			if _, err := os.Stat(file); err == nil {
+				r.input, r.from = r.url, file
+				continue
+			}
+		}
+		missing = append(missing, r.url)
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("importmap: not in node_modules or the cache:\n  %s", strings.Join(missing, "\n  "))
+	}
+
+	out := filepath.Join(dir, filepath.FromSlash(Dir))
+	if err := os.RemoveAll(out); err != nil {
+		return nil, err
+	}
+	var js, css []api.EntryPoint
+	for _, r := range reqs {
+		if r.css {
+			css = append(css, api.EntryPoint{InputPath: r.input, OutputPath: r.name})
+		} else {
+			js = append(js, api.EntryPoint{InputPath: r.input, OutputPath: r.name})
+		}
+	}
+	for _, b := range []struct {
+		name    string
+		entries []api.EntryPoint
+		loader  api.Loader
+	}{{"vendor scripts", js, api.LoaderJS}, {"vendor styles", css, api.LoaderCSS}} {
+		if len(b.entries) == 0 {
+			continue
+		}
+		result := api.Build(api.BuildOptions{
+			AbsWorkingDir:       dir,
+			EntryPointsAdvanced: b.entries,
+			Bundle:              true,
+			// One copy of modules shared between entries, such as the lit
+			// runtime behind lit and lit/decorators.js.
+			Splitting:         b.loader == api.LoaderJS,
+			Format:            api.FormatESModule,
+			Outdir:            out,
+			ChunkNames:        "chunks/[name]-[hash]",
+			MinifyWhitespace:  o.Minify,
+			MinifyIdentifiers: o.Minify,
+			MinifySyntax:      o.Minify,
+			Plugins:           []api.Plugin{cachePlugin(o.Cache, b.loader)},
+			Write:             true,
+			LogLevel:          api.LogLevelSilent,
+		})
+		if len(result.Errors) > 0 {
+			return nil, &assets.BuildError{Bundle: b.name, Messages: result.Errors}
+		}
+	}
+
+	m := Manifest{Files: map[string]string{}}
+	var entries []Entry
+	for _, r := range reqs {
+		file := Dir + "/" + r.name + ".js"
+		if r.css {
+			file = Dir + "/" + r.name + ".css"
+		}
+		m.Files[r.url] = file
+		entries = append(entries, Entry{URL: r.url, File: file, From: r.from})
+	}
+	b, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return entries, os.WriteFile(filepath.Join(dir, filepath.FromSlash(ManifestFile)), append(b, '\n'), 0o644)
+}
+
+// request is one URL on its way through Vendor.
+type request struct {
+	url   string
+	name  string   // output file name without extension
+	specs []string // node_modules specifiers to try, in order
+	css   bool
+
+	input string // esbuild entry point: a resolved file or the URL itself
+	from  string
+}
+
+// resolveModules looks the requests up in node_modules with esbuild's own
+// resolver, so package exports, conditions and the style field behave as
+// they do in a build. The build itself bundles nothing.
+func resolveModules(dir string, reqs []*request) error {
+	result := api.Build(api.BuildOptions{
+		AbsWorkingDir: dir,
+		Stdin:         &api.StdinOptions{},
+		LogLevel:      api.LogLevelSilent,
+		Plugins: []api.Plugin{{
+			Name: "resolve-importmap",
+			Setup: func(pb api.PluginBuild) {
+				pb.OnStart(func() (api.OnStartResult, error) {
+					for _, r := range reqs {
+						kind := api.ResolveJSImportStatement
+						if r.css {
+							kind = api.ResolveCSSImportRule
+						}
+						for _, spec := range r.specs {
+							res := pb.Resolve(spec, api.ResolveOptions{Kind: kind, ResolveDir: dir})
+							if len(res.Errors) == 0 && res.Path != "" && !res.External {
+								r.input, r.from = res.Path, spec
+								break
+							}
+						}
+					}
+					return api.OnStartResult{}, nil
+				})
+			},
+		}},
+	})
+	if len(result.Errors) > 0 {
+		return &assets.BuildError{Bundle: "resolve", Messages: result.Errors}
+	}
+	return nil
+}
+
+// packageSpecs derives the package a jsDelivr or unpkg URL comes from and
+// the node_modules specifiers it stands for: /npm/lit@3/decorators.js/+esm
+// is lit/decorators.js and /gh/starfederation/datastar@v1/bundles/datastar.js
+// is bundles/datastar.js of the datastar package. CDNs serve files by path
+// regardless of a package's exports, so the plain file is tried after the
+// specifier, and a bare package URL such as unpkg.com/open-props is the
+// file the CDN's field in package.json names.
+func packageSpecs(dir, rawURL string) (pkg string, specs []string) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", nil
+	}
+	segs := strings.Split(strings.Trim(u.Path, "/"), "/")
+	field, esm := "", false
+	switch u.Host {
+	case "cdn.jsdelivr.net":
+		if len(segs) >= 3 && segs[0] == "gh" {
+			owner, repo, sub := segs[1], stripVersion(segs[2]), strings.Join(segs[3:], "/")
+			for _, pkg := range []string{"@" + owner + "/" + repo, repo} {
+				specs = append(specs, packageFiles(dir, pkg, sub, "")...)
+			}
+			return repo, specs
+		}
+		if segs[0] != "npm" {
+			return "", nil
+		}
+		segs, field = segs[1:], "jsdelivr"
+		if len(segs) > 0 && segs[len(segs)-1] == "+esm" {
+			segs, esm = segs[:len(segs)-1], true
+		}
+	case "unpkg.com":
+		field = "unpkg"
+	default:
+		return "", nil
+	}
+
+	n := 1
+	if len(segs) > 0 && strings.HasPrefix(segs[0], "@") {
+		n = 2
+	}
+	if len(segs) < n || segs[0] == "" {
+		return "", nil
+	}
+	pkg = stripVersion(segs[n-1])
+	if n == 2 {
+		pkg = segs[0] + "/" + pkg
+	}
+	sub := strings.Join(segs[n:], "/")
+	if esm {
+		// jsDelivr's +esm builds follow the package's own entry points.
+		return pkg, []string{join(pkg, sub)}
+	}
+	return pkg, packageFiles(dir, pkg, sub, field)
+}
+
+// packageFiles is the specifier for sub of pkg followed by the file a CDN
+// would serve for it, when dir has one.
+func packageFiles(dir, pkg, sub, field string) []string {
+	specs := []string{join(pkg, sub)}
+	if sub == "" && field != "" {
+		sub = packageField(dir, pkg, field)
+	}
+	if sub == "" {
+		return specs
+	}
+	file := filepath.Join(dir, "node_modules", filepath.FromSlash(pkg), filepath.FromSlash(sub))
+	if _, err := os.Stat(file); err == nil {
+		specs = append(specs, file)
+	}
+	return specs
+}
+
+// packageField reads a string field from node_modules/<pkg>/package.json.
+func packageField(dir, pkg, field string) string {
+	b, err := os.ReadFile(filepath.Join(dir, "node_modules", filepath.FromSlash(pkg), "package.json"))
+	if err != nil {
+		return ""
+	}
+	var fields map[string]any
+	if json.Unmarshal(b, &fields) != nil {
+		return ""
+	}
+	s, _ := fields[field].(string)
+	if s == "" {
+		return ""
+	}
+	return strings.TrimPrefix(path.Clean(s), "./")
+}
+
+func stripVersion(seg string) string {
+	if i := strings.LastIndex(seg, "@"); i > 0 {
+		return seg[:i]
+	}
+	return seg
+}
+
+func join(pkg, sub string) string {
+	if sub == "" {
+		return pkg
+	}
+	return pkg + "/" + sub
+}
+
+// outputName turns a specifier into a flat file name:
+// lit/directives/repeat.js becomes lit-directives-repeat.
+func outputName(spec string) string {
+	spec = strings.TrimSuffix(strings.TrimPrefix(spec, "@"), ".js")
+	return strings.ReplaceAll(spec, "/", "-")
+}
+
+// cacheFile is where the cache keeps a URL.
+func cacheFile(cache, rawURL string) (string, error) {
+	if cache == "" {
+		return "", errors.New("importmap: no cache directory")
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", err
+	}
+	p := path.Clean("/" + u.Path)
+	if u.Host == "" || p == "/" {
+		return "", fmt.Errorf("importmap: cannot cache %s", rawURL)
+	}
+	return filepath.Join(cache, u.Host, filepath.FromSlash(p)), nil
+}
+
+// cachePlugin loads URLs from the cache. Imports inside a cached file are
+// resolved against its URL, so the absolute /npm/... paths jsDelivr's +esm
+// builds use find their targets in the same mirror.
+func cachePlugin(cache string, loader api.Loader) api.Plugin {
+	return api.Plugin{
+		Name: "cdn-cache",
+		Setup: func(pb api.PluginBuild) {
+			pb.OnResolve(api.OnResolveOptions{Filter: `^https?://`}, func(args api.OnResolveArgs) (api.OnResolveResult, error) {
+				return api.OnResolveResult{Path: args.Path, Namespace: "cdn"}, nil
+			})
+			pb.OnResolve(api.OnResolveOptions{Filter: `.*`, Namespace: "cdn"}, func(args api.OnResolveArgs) (api.OnResolveResult, error) {
+				if !strings.HasPrefix(args.Path, "/") && !strings.HasPrefix(args.Path, ".") {
+					return api.OnResolveResult{}, fmt.Errorf("bare import %q in a cached file", args.Path)
+				}
+				base, err := url.Parse(args.Importer)
+				if err != nil {
+					return api.OnResolveResult{}, err
+				}
+				ref, err := url.Parse(args.Path)
+				if err != nil {
+					return api.OnResolveResult{}, err
+				}
+				return api.OnResolveResult{Path: base.ResolveReference(ref).String(), Namespace: "cdn"}, nil
+			})
+			pb.OnLoad(api.OnLoadOptions{Filter: `.*`, Namespace: "cdn"}, func(args api.OnLoadArgs) (api.OnLoadResult, error) {
+				file, err := cacheFile(cache, args.Path)
+				if err != nil {
+					return api.OnLoadResult{}, err
+				}
+				b, err := os.ReadFile(file)
+				if err != nil {
+					return api.OnLoadResult{}, err
+				}
+				contents := string(b)
+				return api.OnLoadResult{Contents: &contents, Loader: loader}, nil
+			})
+		},
+	}
+}
+
+// Manifest maps CDN URLs to their vendored files.
+type Manifest struct {
+	Files map[string]string `json:"files"` // URL -> path relative to the site root
+}
+
+// Load reads the manifest from a site root. The error wraps fs.ErrNotExist
+// when nothing has been vendored.
+func Load(fsys fs.FS) (*Manifest, error) {
+	b, err := fs.ReadFile(fsys, ManifestFile)
+	if err != nil {
+		return nil, err
+	}
+	var m Manifest
+	if err := json.Unmarshal(b, &m); err != nil {
+		return nil, fmt.Errorf("%s: %w", ManifestFile, err)
+	}
+	return &m, nil
+}
+
+// Rewrite points every vendored URL in a page at its local copy.
+func (m *Manifest) Rewrite(page []byte) []byte {
+	// Longest first, so a URL that prefixes another cannot win.
+	urls := slices.SortedFunc(maps.Keys(m.Files), func(a, b string) int { return cmp.Compare(len(b), len(a)) })
+	var pairs []string
+	for _, u := range urls {
+		pairs = append(pairs, u, "./"+m.Files[u])
+	}
+	return []byte(strings.NewReplacer(pairs...).Replace(string(page)))
+}
@@ -0,0 +1,207 @@
+// Package livereload rebuilds the demo assets when their sources change and
+// pushes the result to open pages over a Datastar SSE stream: a full reload
+// after a component rebuild, a stylesheet swap after a CSS-only rebuild,
+// and an error overlay while a build is broken.
+package livereload
+
+import (
+	"fmt"
+	"html"
+	"log/slog"
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/evanw/esbuild/pkg/api"
+
+	"github.com/yacobolo/datastar-lit-examples/internal/assets"
+	"github.com/yacobolo/datastar-lit-examples/internal/datastar"
+)
+
+// OverlayID is the id of the element build errors are patched into.
+const OverlayID = "dev-overlay"
+
+// reloadJS and swapCSSJS run in the page. The stylesheet swap re-requests
+// the bundle with a cache-busting query so the page keeps its state.
+const (
+	reloadJS  = `location.reload()`
+	swapCSSJS = `document.querySelectorAll('link[rel="stylesheet"][href*="demo/dist/styles"]').forEach(l => { const u = new URL(l.href); u.searchParams.set('v', Date.now()); l.href = u.href })`
+)
+
+type event struct {
+	script  string // run in the page, if set
+	overlay string // replaces the overlay element
+}
+
+// Reloader watches the asset sources and fans build results out to every
+// connected page.
+type Reloader struct {
+	// OnBuild and OnReload, if set, are told about every build and every
+	// reload ("page" or "css") pushed to the pages. Set them before Watch.
+	OnBuild  func(bundle string, ok bool)
+	OnReload func(kind string)
+
+	mu      sync.Mutex
+	clients map[chan event]struct{}
+	errors  map[string][]api.Message // bundle name -> errors of its last build
+	built   map[string]bool
+}
+
+// New returns a Reloader with no pages connected.
+func New() *Reloader {
+	return &Reloader{
+		clients: map[chan event]struct{}{},
+		errors:  map[string][]api.Message{},
+		built:   map[string]bool{},
+	}
+}
+
+// Watch starts incremental esbuild builds of every bundle that rerun
+// whenever an input file changes. The returned function stops them.
+func (l *Reloader) Watch(o assets.Options) (stop func(), err error) {
+	bundles, err := assets.Bundles(o)
+	if err != nil {
+		return nil, err
+	}
+
+	var contexts []api.BuildContext
+	stop = func() {
+		for _, ctx := range contexts {
+			ctx.Dispose()
+		}
+	}
+	for _, b := range bundles {
+		opts := b.Options
+		opts.Plugins = append(opts.Plugins, api.Plugin{
+			Name: "livereload",
+			Setup: func(pb api.PluginBuild) {
+				pb.OnEnd(func(result *api.BuildResult) (api.OnEndResult, error) {
+					l.record(b.Name, result.Errors)
+					return api.OnEndResult{}, nil
+				})
+			},
+		})
+		ctx, cerr := api.Context(opts)
+		if cerr != nil {
+			stop()
+			return nil, fmt.Errorf("livereload: %s: %w", b.Name, cerr)
+		}
+		contexts = append(contexts, ctx)
+		if err := ctx.Watch(api.WatchOptions{}); err != nil {
+			stop()
+			return nil, fmt.Errorf("livereload: watch %s: %w", b.Name, err)
+		}
+	}
+	return stop, nil
+}
+
+// record stores the outcome of one build of bundle and tells the pages.
+// The initial build of each bundle only reports errors; there is nothing
+// stale to reload yet.
+func (l *Reloader) record(bundle string, errs []api.Message) {
+	l.mu.Lock()
+	first := !l.built[bundle]
+	l.built[bundle] = true
+	hadErrors := len(l.errors) > 0
+	if len(errs) > 0 {
+		l.errors[bundle] = errs
+	} else {
+		delete(l.errors, bundle)
+	}
+	overlay := l.overlayLocked()
+	l.mu.Unlock()
+
+	if l.OnBuild != nil {
+		l.OnBuild(bundle, len(errs) == 0)
+	}
+	switch {
+	case len(errs) > 0:
+		slog.Error("livereload: build failed", "bundle", bundle, "errors", strings.Join(assets.Format(errs), ""))
+		l.broadcast(event{overlay: overlay})
+	case overlay != emptyOverlay:
+		// This bundle is fixed but another is still broken.
+		l.broadcast(event{overlay: overlay})
+	case first:
+		slog.Info("livereload: built", "bundle", bundle)
+	default:
+		slog.Info("livereload: rebuilt", "bundle", bundle)
+		ev, kind := event{script: reloadJS}, "page"
+		if bundle == "styles" {
+			ev.script, kind = swapCSSJS, "css"
+		}
+		if hadErrors {
+			ev.overlay = overlay
+		}
+		l.broadcast(ev)
+		if l.OnReload != nil {
+			l.OnReload(kind)
+		}
+	}
+}
+
+var emptyOverlay = fmt.Sprintf(`<div id="%s"></div>`, OverlayID)
+
+func (l *Reloader) overlayLocked() string {
+	if len(l.errors) == 0 {
+		return emptyOverlay
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, `<div id="%s" style="position:fixed;inset:0;z-index:2000;overflow:auto;padding:2rem;background:rgb(15 15 23 / .95);color:#fca5a5;font:13px/1.5 ui-monospace,monospace">`, OverlayID)
+	for _, name := range slices.Sorted(maps.Keys(l.errors)) {
+		fmt.Fprintf(&b, `<h3 style="margin:0 0 .5rem;color:#f87171">%s build failed</h3>`, html.EscapeString(name))
+		fmt.Fprintf(&b, `<pre style="white-space:pre-wrap;margin:0 0 1.5rem">%s</pre>`,
+			html.EscapeString(strings.Join(assets.Format(l.errors[name]), "")))
+	}
+	b.WriteString(`</div>`)
+	return b.String()
+}
+
+func (l *Reloader) broadcast(ev event) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for ch := range l.clients {
+		select {
+		case ch <- ev:
+		default:
+			// A page that cannot keep up only misses intermediate states.
+		}
+	}
+}
+
+// ServeHTTP holds the SSE stream a dev page opens on load.
+func (l *Reloader) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ch := make(chan event, 8)
+	l.mu.Lock()
+	l.clients[ch] = struct{}{}
+	overlay := l.overlayLocked()
+	l.mu.Unlock()
+	defer func() {
+		l.mu.Lock()
+		delete(l.clients, ch)
+		l.mu.Unlock()
+	}()
+
+	sse := datastar.NewSSE(w, r)
+	if err := sse.PatchElements(overlay); err != nil {
+		return
+	}
+	for {
+		select {
+		case <-sse.Done():
+			return
+		case ev := <-ch:
+			if ev.overlay != "" {
+				if err := sse.PatchElements(ev.overlay); err != nil {
+					return
+				}
+			}
+			if ev.script != "" {
+				if err := sse.ExecuteScript(ev.script); err != nil {
+					return
+				}
+			}
+		}
+	}
+}
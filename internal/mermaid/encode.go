@@ -0,0 +1,77 @@
+// Package mermaid converts flow diagrams to and from Mermaid flowcharts.
+package mermaid
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/yacobolo/datastar-lit-examples/internal/signals"
+)
+
+// Encode writes f as a left-to-right flowchart of circles, in the order of
+// f's nodes; Mermaid lays the chart out itself, so positions are dropped.
+// Each node gets a style line with its fill and the canvas's white stroke
+// and text. Edges to missing nodes are left out, since Mermaid would
+// invent the node.
+func Encode(w io.Writer, f signals.Flow) error {
+	ids := nodeIDs(f.Nodes)
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "flowchart LR")
+	for i, n := range f.Nodes {
+		fmt.Fprintf(bw, "    %s((%s))\n", ids[i], label(n.Label))
+	}
+	first := map[string]string{}
+	for i, n := range f.Nodes {
+		if _, ok := first[n.ID]; !ok {
+			first[n.ID] = ids[i]
+		}
+	}
+	for _, e := range f.Edges {
+		source, ok1 := first[e.Source]
+		target, ok2 := first[e.Target]
+		if !ok1 || !ok2 {
+			continue
+		}
+		fmt.Fprintf(bw, "    %s --> %s\n", source, target)
+	}
+	for i, n := range f.Nodes {
+		color := n.Color
+		if color == "" {
+			color = signals.DefaultNodeColor
+		}
+		fmt.Fprintf(bw, "    style %s fill:%s,stroke:#ffffff,stroke-width:2px,color:#ffffff\n", ids[i], color)
+	}
+	return bw.Flush()
+}
+
+var unsafeID = regexp.MustCompile(`[^A-Za-z0-9_]`)
+
+// nodeIDs returns a Mermaid identifier for every node: the flow ID with
+// anything but letters, digits and _ replaced, prefixed with n so numeric
+// IDs and keywords such as end stay valid, and suffixed where that makes
+// two the same.
+func nodeIDs(nodes []signals.FlowNode) []string {
+	ids := make([]string, len(nodes))
+	used := map[string]bool{}
+	for i, n := range nodes {
+		base := "n" + unsafeID.ReplaceAllString(n.ID, "_")
+		id := base
+		for k := 2; used[id]; k++ {
+			id = base + "_" + strconv.Itoa(k)
+		}
+		used[id] = true
+		ids[i] = id
+	}
+	return ids
+}
+
+// label quotes s so any punctuation is taken literally. Quotes and the
+// characters Mermaid decodes as entities are written as entity codes.
+func label(s string) string {
+	r := strings.NewReplacer(`"`, "#quot;", "#", "#35;", "\n", "<br>", "\r", "")
+	return `"` + r.Replace(s) + `"`
+}
@@ -0,0 +1,453 @@
+package mermaid
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/yacobolo/datastar-lit-examples/internal/flowgraph"
+	"github.com/yacobolo/datastar-lit-examples/internal/signals"
+)
+
+// Error is a syntax error in Mermaid source. Line and Col are 1-based; Col
+// counts runes.
+type Error struct {
+	Line, Col int
+	Msg       string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%d:%d: %s", e.Line, e.Col, e.Msg)
+}
+
+// Parse reads a flowchart (or graph) into a flow. It understands node
+// declarations in any of the bracket shapes, chains of links in all their
+// styles (with or without text), & between nodes, subgraphs (which are
+// flattened), and node colors given by style, classDef with class or
+// :::class. Link text, click, linkStyle and the like are skipped.
+//
+// Nodes get IDs 1, 2, ... in the order they are first mentioned, and
+// edges likewise. A chart with more than flowgraph.MaxNodes nodes or
+// flowgraph.MaxEdges edges is an error. Mermaid has no positions, so the chart is laid out with
+// flowgraph.Layered in the direction of its header. The result's Config
+// is zero.
+func Parse(src []byte, o flowgraph.Options) (signals.Flow, error) {
+	p := &parser{index: map[string]int{}, classes: map[string]string{}, styles: map[string]string{}, classOf: map[string]string{}}
+	lines := strings.Split(strings.ReplaceAll(string(src), "\r\n", "\n"), "\n")
+	header := false
+	depth := 0
+	frontMatter := false
+	for i, text := range lines {
+		s := &scanner{src: text, line: i + 1}
+		s.skipSpace()
+		switch {
+		case i == 0 && strings.TrimSpace(text) == "---":
+			frontMatter = true
+			continue
+		case frontMatter:
+			frontMatter = strings.TrimSpace(text) != "---"
+			continue
+		case s.done() || strings.HasPrefix(s.rest(), "%%"):
+			continue
+		}
+
+		if !header {
+			at := *s
+			kw := s.word()
+			if kw != "flowchart" && kw != "graph" {
+				return signals.Flow{}, at.errorf("expected flowchart or graph, got %q", strings.Fields(at.rest())[0])
+			}
+			s.skipSpace()
+			at = *s
+			dir := s.word()
+			switch dir {
+			case "", "LR", "RL":
+			case "TB", "TD", "BT":
+				o.Vertical = true
+			default:
+				return signals.Flow{}, at.errorf("unknown direction %q", dir)
+			}
+			header = true
+			if err := s.endStatement(); err != nil {
+				return signals.Flow{}, err
+			}
+		}
+
+		for !s.done() {
+			if err := p.statement(s, &depth); err != nil {
+				return signals.Flow{}, err
+			}
+		}
+	}
+	if !header {
+		return signals.Flow{}, &Error{Line: len(lines), Col: 1, Msg: "expected flowchart or graph, got end of input"}
+	}
+	if depth > 0 {
+		return signals.Flow{}, &Error{Line: len(lines), Col: 1, Msg: "subgraph not closed with end"}
+	}
+	return p.flow(o), nil
+}
+
+type parser struct {
+	nodes   []*node
+	index   map[string]int // Mermaid ID to position in nodes
+	edges   [][2]int
+	classes map[string]string // classDef name to fill color
+	styles  map[string]string // Mermaid ID to fill color from style
+	classOf map[string]string // Mermaid ID to class name
+}
+
+type node struct {
+	name, label string
+}
+
+// statement parses one statement of s, up to a ; or the end of the line.
+func (p *parser) statement(s *scanner, depth *int) error {
+	start := *s
+	switch kw := s.word(); kw {
+	case "style":
+		s.skipSpace()
+		id, props := s.word(), s.restOfStatement()
+		if c, ok := fill(props); ok {
+			p.styles[id] = c
+		}
+		return s.endStatement()
+	case "classDef":
+		s.skipSpace()
+		names, props := s.word(), s.restOfStatement()
+		if c, ok := fill(props); ok {
+			for _, name := range strings.Split(names, ",") {
+				p.classes[name] = c
+			}
+		}
+		return s.endStatement()
+	case "class":
+		s.skipSpace()
+		ids := s.until(" \t")
+		s.skipSpace()
+		class := s.word()
+		for _, id := range strings.Split(ids, ",") {
+			if id = strings.TrimSpace(id); id != "" {
+				p.classOf[id] = class
+			}
+		}
+		return s.endStatement()
+	case "subgraph":
+		*depth++
+		s.restOfStatement()
+		return s.endStatement()
+	case "end":
+		if s.done() || s.peek() == ';' || unicode.IsSpace(s.peek()) {
+			if *depth == 0 {
+				return start.errorf("end without subgraph")
+			}
+			*depth--
+			return s.endStatement()
+		}
+	case "direction", "click", "linkStyle", "accTitle", "accDescr":
+		s.restOfStatement()
+		return s.endStatement()
+	}
+	*s = start
+
+	from, err := p.nodeGroup(s)
+	if err != nil {
+		return err
+	}
+	for {
+		s.skipSpace()
+		if s.done() || s.peek() == ';' {
+			break
+		}
+		link := *s
+		if !s.link() {
+			return s.errorf("expected a link such as --> or the end of the statement, got %q", s.rest())
+		}
+		s.skipSpace()
+		to, err := p.nodeGroup(s)
+		if err != nil {
+			return err
+		}
+		for _, a := range from {
+			for _, b := range to {
+				if len(p.edges) == flowgraph.MaxEdges {
+					return link.errorf("too many edges, at most %d are allowed", flowgraph.MaxEdges)
+				}
+				p.edges = append(p.edges, [2]int{a, b})
+			}
+		}
+		from = to
+	}
+	return s.endStatement()
+}
+
+// nodeGroup parses node (& node)*.
+func (p *parser) nodeGroup(s *scanner) ([]int, error) {
+	var ids []int
+	for {
+		s.skipSpace()
+		id, err := p.nodeRef(s)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+		s.skipSpace()
+		if s.done() || s.peek() != '&' {
+			return ids, nil
+		}
+		s.advance()
+	}
+}
+
+// shapes pairs each opening bracket sequence with its closer, longest
+// first so (( is not read as (.
+var shapes = []struct{ open, close string }{
+	{"(((", ")))"}, {"((", "))"}, {"([", "])"}, {"[(", ")]"}, {"[[", "]]"},
+	{"{{", "}}"}, {"[/", "]"}, {`[\`, "]"}, {"(", ")"}, {"[", "]"}, {"{", "}"}, {">", "]"},
+}
+
+// nodeRef parses ID, an optional shape with text and an optional :::class.
+func (p *parser) nodeRef(s *scanner) (int, error) {
+	at := *s
+	name := s.word()
+	if name == "" {
+		if s.done() {
+			return 0, s.errorf("expected a node, got end of line")
+		}
+		return 0, s.errorf("expected a node, got %q", s.rest())
+	}
+	id, ok := p.node(name)
+	if !ok {
+		return 0, at.errorf("too many nodes, at most %d are allowed", flowgraph.MaxNodes)
+	}
+
+	for _, sh := range shapes {
+		if !strings.HasPrefix(s.rest(), sh.open) {
+			continue
+		}
+		open := *s
+		for range utf8.RuneCountInString(sh.open) {
+			s.advance()
+		}
+		var text string
+		if !s.done() && s.peek() == '"' {
+			q, err := s.quoted()
+			if err != nil {
+				return 0, err
+			}
+			text = q
+			if !strings.HasPrefix(s.rest(), sh.close) {
+				return 0, s.errorf("expected %s to close %s", sh.close, sh.open)
+			}
+		} else {
+			i := strings.Index(s.rest(), sh.close)
+			if i < 0 {
+				return 0, open.errorf("missing %s to close %s", sh.close, sh.open)
+			}
+			text = s.rest()[:i]
+			for range utf8.RuneCountInString(text) {
+				s.advance()
+			}
+			if sh.open == "[/" || sh.open == `[\` {
+				text = strings.TrimRight(text, `/\`)
+			}
+		}
+		for range utf8.RuneCountInString(sh.close) {
+			s.advance()
+		}
+		p.nodes[id].label = decode(text)
+		break
+	}
+
+	if strings.HasPrefix(s.rest(), ":::") {
+		s.advance()
+		s.advance()
+		s.advance()
+		p.classOf[name] = s.word()
+	}
+	return id, nil
+}
+
+// node returns the index of the named node, creating it unless that would
+// make more than flowgraph.MaxNodes.
+func (p *parser) node(name string) (int, bool) {
+	if i, ok := p.index[name]; ok {
+		return i, true
+	}
+	if len(p.nodes) == flowgraph.MaxNodes {
+		return 0, false
+	}
+	p.index[name] = len(p.nodes)
+	p.nodes = append(p.nodes, &node{name: name, label: name})
+	return len(p.nodes) - 1, true
+}
+
+func (p *parser) flow(o flowgraph.Options) signals.Flow {
+	f := signals.Flow{Nodes: []signals.FlowNode{}, Edges: []signals.FlowEdge{}}
+	for i, n := range p.nodes {
+		color, ok := p.styles[n.name]
+		if !ok {
+			color = p.classes[p.classOf[n.name]]
+		}
+		f.Nodes = append(f.Nodes, signals.FlowNode{ID: strconv.Itoa(i + 1), Label: n.label, Color: color})
+	}
+	for i, e := range p.edges {
+		f.Edges = append(f.Edges, signals.FlowEdge{
+			ID:     strconv.Itoa(i + 1),
+			Source: strconv.Itoa(e[0] + 1),
+			Target: strconv.Itoa(e[1] + 1),
+		})
+	}
+	f.Nodes = flowgraph.Layout(flowgraph.Layered, f, o, nil)
+	return f
+}
+
+var fillProp = regexp.MustCompile(`(?:^|[,;\s])fill\s*:\s*([^,;\s]+)`)
+
+// fill returns the fill color among style properties such as
+// fill:#f9f,stroke:#333.
+func fill(props string) (string, bool) {
+	m := fillProp.FindStringSubmatch(props)
+	if m == nil {
+		return "", false
+	}
+	return signals.ParseColor(m[1])
+}
+
+var (
+	entity    = regexp.MustCompile(`#(\d+|[a-z]+);`)
+	lineBreak = regexp.MustCompile(`(?i)<br\s*/?>`)
+)
+
+// decode turns node text into a plain label: entity codes such as #quot;
+// and #35; are resolved and <br> line breaks become spaces.
+func decode(text string) string {
+	text = lineBreak.ReplaceAllString(text, " ")
+	text = entity.ReplaceAllStringFunc(text, func(m string) string {
+		name := m[1 : len(m)-1]
+		if n, err := strconv.Atoi(name); err == nil {
+			return string(rune(n))
+		}
+		if r, ok := map[string]string{"quot": `"`, "amp": "&", "lt": "<", "gt": ">", "apos": "'"}[name]; ok {
+			return r
+		}
+		return m
+	})
+	return strings.Join(strings.Fields(text), " ")
+}
+
+// scanner walks one line.
+type scanner struct {
+	src       string
+	pos       int
+	line, col int
+}
+
+func (s *scanner) done() bool   { return s.pos >= len(s.src) }
+func (s *scanner) rest() string { return s.src[s.pos:] }
+
+func (s *scanner) peek() rune {
+	r, _ := utf8.DecodeRuneInString(s.rest())
+	return r
+}
+
+func (s *scanner) advance() rune {
+	r, size := utf8.DecodeRuneInString(s.rest())
+	s.pos += size
+	s.col++
+	return r
+}
+
+func (s *scanner) errorf(format string, args ...any) error {
+	return &Error{Line: s.line, Col: s.col + 1, Msg: fmt.Sprintf(format, args...)}
+}
+
+func (s *scanner) skipSpace() {
+	for !s.done() && unicode.IsSpace(s.peek()) {
+		s.advance()
+	}
+}
+
+// word reads a Mermaid identifier: letters, digits, _ and -, as long as
+// the - does not start a link.
+func (s *scanner) word() string {
+	start := s.pos
+	for !s.done() {
+		r := s.peek()
+		if r == '-' && strings.HasPrefix(s.rest(), "--") || r == '-' && strings.HasPrefix(s.rest(), "-.") {
+			break
+		}
+		if r != '_' && r != '-' && !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			break
+		}
+		s.advance()
+	}
+	return s.src[start:s.pos]
+}
+
+// until reads up to the next of the runes in stop, ; or the end.
+func (s *scanner) until(stop string) string {
+	start := s.pos
+	for !s.done() && s.peek() != ';' && !strings.ContainsRune(stop, s.peek()) {
+		s.advance()
+	}
+	return s.src[start:s.pos]
+}
+
+func (s *scanner) restOfStatement() string {
+	return strings.TrimSpace(s.until(""))
+}
+
+// endStatement consumes the ; that ends a statement, if any, and
+// requires nothing else to follow on the line but another statement.
+func (s *scanner) endStatement() error {
+	s.skipSpace()
+	if s.done() {
+		return nil
+	}
+	if s.peek() == ';' {
+		s.advance()
+		s.skipSpace()
+		return nil
+	}
+	return s.errorf("unexpected %q", s.rest())
+}
+
+func (s *scanner) quoted() (string, error) {
+	open := *s
+	s.advance()
+	i := strings.IndexByte(s.rest(), '"')
+	if i < 0 {
+		return "", open.errorf("string not terminated")
+	}
+	text := s.rest()[:i]
+	for range utf8.RuneCountInString(text) + 1 {
+		s.advance()
+	}
+	return text, nil
+}
+
+var (
+	// A link with text in the middle: -- text -->, == text ==>, -. text .->
+	textLink = regexp.MustCompile(`^<?(?:--|==|-\.)\s+[^-=.|>][^|]*?\s*(?:-{2,}[>ox-]?|={2,}[>=]?|\.-+>?)`)
+	// A plain link, optionally with |text|: -->, ---, -.->, ==>, --o, ~~~, <-->
+	plainLink = regexp.MustCompile(`^(?:<?(?:-{2,}|={2,}|-\.+-)[>ox]?|~{3,})(?:\s*\|[^|]*\|)?`)
+)
+
+// link consumes a link if one starts here.
+func (s *scanner) link() bool {
+	m := textLink.FindString(s.rest())
+	if m == "" {
+		m = plainLink.FindString(s.rest())
+	}
+	if m == "" {
+		return false
+	}
+	for range utf8.RuneCountInString(m) {
+		s.advance()
+	}
+	return true
+}
@@ -0,0 +1,212 @@
+package mermaid
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/yacobolo/datastar-lit-examples/internal/flowgraph"
+	"github.com/yacobolo/datastar-lit-examples/internal/signals"
+)
+
+// summary renders a flow's nodes as label/color and its edges as
+// source->target, leaving out the computed positions.
+func summary(f signals.Flow) (nodes, edges []string) {
+	for i, n := range f.Nodes {
+		if want := fmt.Sprint(i + 1); n.ID != want {
+			nodes = append(nodes, "bad ID "+n.ID)
+		}
+		nodes = append(nodes, n.Label+"/"+n.Color)
+	}
+	for i, e := range f.Edges {
+		if want := fmt.Sprint(i + 1); e.ID != want {
+			edges = append(edges, "bad ID "+e.ID)
+		}
+		edges = append(edges, e.Source+"->"+e.Target)
+	}
+	return nodes, edges
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name  string
+		src   string
+		nodes []string
+		edges []string
+	}{
+		{
+			name:  "chain",
+			src:   "flowchart LR\n  A --> B --> C\n",
+			nodes: []string{"A/", "B/", "C/"},
+			edges: []string{"1->2", "2->3"},
+		},
+		{
+			name:  "graph header and semicolons",
+			src:   "graph TD; A-->B; B-->C",
+			nodes: []string{"A/", "B/", "C/"},
+			edges: []string{"1->2", "2->3"},
+		},
+		{
+			name: "shapes",
+			src: "flowchart\n" +
+				"  a[Box] --> b(Round) --> c((Circle)) --> d{Choice}\n" +
+				"  e([Stadium]) --> f[[Sub]] --> g[(Db)] --> h{{Hex}}\n" +
+				"  i>Flag] --> j[/Lean/] --> k(((Double)))\n",
+			nodes: []string{"Box/", "Round/", "Circle/", "Choice/", "Stadium/", "Sub/", "Db/", "Hex/", "Flag/", "Lean/", "Double/"},
+			edges: []string{"1->2", "2->3", "3->4", "5->6", "6->7", "7->8", "9->10", "10->11"},
+		},
+		{
+			name:  "link styles and text",
+			src:   "flowchart LR\n  A -- text --> B -.-> C ==> D --- E\n  A -->|label| E\n  A -. dotted .-> C\n",
+			nodes: []string{"A/", "B/", "C/", "D/", "E/"},
+			edges: []string{"1->2", "2->3", "3->4", "4->5", "1->5", "1->3"},
+		},
+		{
+			name:  "ampersands",
+			src:   "flowchart LR\n  A & B --> C & D\n",
+			nodes: []string{"A/", "B/", "C/", "D/"},
+			edges: []string{"1->3", "1->4", "2->3", "2->4"},
+		},
+		{
+			name:  "quoted text and entities",
+			src:   "flowchart LR\n  A[\"Say #quot;hi#quot;\"] --> B[\"one<br>two\"]\n  C[a #35; b]\n",
+			nodes: []string{`Say "hi"/`, "one two/", "a # b/"},
+			edges: []string{"1->2"},
+		},
+		{
+			name: "colors from style, classDef and class",
+			src: "flowchart LR\n" +
+				"  A:::hot --> B --> C --> D\n" +
+				"  classDef hot fill:#f00,stroke:#333\n" +
+				"  classDef cold fill:blue\n" +
+				"  class B,C cold\n" +
+				"  style C fill:#00ff00\n",
+			nodes: []string{"A/#ff0000", "B/#0000ff", "C/#00ff00", "D/"},
+			edges: []string{"1->2", "2->3", "3->4"},
+		},
+		{
+			name: "subgraphs, comments and skipped statements",
+			src: "---\ntitle: Example\n---\n" +
+				"%% a comment\n" +
+				"flowchart TB\n" +
+				"  subgraph one [First]\n" +
+				"    direction LR\n" +
+				"    A --> B\n" +
+				"  end\n" +
+				"  click A callback\n" +
+				"  linkStyle 0 stroke:red\n" +
+				"  B --> C\n",
+			nodes: []string{"A/", "B/", "C/"},
+			edges: []string{"1->2", "2->3"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := Parse([]byte(tt.src), flowgraph.DefaultOptions)
+			if err != nil {
+				t.Fatal(err)
+			}
+			nodes, edges := summary(f)
+			if !reflect.DeepEqual(nodes, tt.nodes) || !reflect.DeepEqual(edges, tt.edges) {
+				t.Errorf("Parse() = %q %q, want %q %q", nodes, edges, tt.nodes, tt.edges)
+			}
+			if f.Config != (signals.FlowConfig{}) {
+				t.Errorf("Config = %+v, want zero", f.Config)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{"empty", "", `1:1: expected flowchart or graph, got end of input`},
+		{"no header", "A --> B", `1:1: expected flowchart or graph, got "A"`},
+		{"bad direction", "flowchart XY", `1:11: unknown direction "XY"`},
+		{"bad node", "flowchart LR\n  A --> B\n  B --> (C)\n", `3:9: expected a node, got "(C)"`},
+		{"unclosed shape", "flowchart LR\n  A[Box --> B\n", `2:4: missing ] to close [`},
+		{"missing target", "flowchart LR\n  A -->\n", `2:8: expected a node, got end of line`},
+		{"stray end", "flowchart LR\n end\n", `2:2: end without subgraph`},
+		{"open subgraph", "flowchart LR\n  subgraph S\n  A\n", `4:1: subgraph not closed with end`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse([]byte(tt.src), flowgraph.DefaultOptions)
+			var perr *Error
+			if !errors.As(err, &perr) {
+				t.Fatalf("Parse() error = %v, want an *Error", err)
+			}
+			if err.Error() != tt.want {
+				t.Errorf("Parse() error = %q, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseLimits(t *testing.T) {
+	var names []string
+	for i := range flowgraph.MaxNodes + 1 {
+		names = append(names, fmt.Sprintf("n%d", i))
+	}
+	nodes := "flowchart LR\n" + strings.Join(names, " & ") + "\n"
+	_, err := Parse([]byte(nodes), flowgraph.DefaultOptions)
+	want := fmt.Sprintf("2:%d: too many nodes, at most %d are allowed",
+		len(strings.Join(names[:flowgraph.MaxNodes], " & "))+4, flowgraph.MaxNodes)
+	if err == nil || err.Error() != want {
+		t.Errorf("%d nodes: error %v, want %q", len(names), err, want)
+	}
+
+	group := strings.Join(names[:50], " & ")
+	edges := "flowchart LR\n" + group + " --> " + group + "\n"
+	_, err = Parse([]byte(edges), flowgraph.DefaultOptions)
+	want = fmt.Sprintf("2:%d: too many edges, at most %d are allowed", len(group)+2, flowgraph.MaxEdges)
+	if err == nil || err.Error() != want {
+		t.Errorf("50×50 edges: error %v, want %q", err, want)
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	flow := signals.Default().Flow
+	flow.Nodes = append(flow.Nodes,
+		signals.FlowNode{ID: "end", Label: `Quote " and # hash`},
+		signals.FlowNode{ID: "two words", Label: "two words", Color: "#123456"})
+	flow.Edges = append(flow.Edges, signals.FlowEdge{ID: "99", Source: "end", Target: "two words"})
+
+	var buf bytes.Buffer
+	if err := Encode(&buf, flow); err != nil {
+		t.Fatal(err)
+	}
+	got, err := Parse(buf.Bytes(), flowgraph.DefaultOptions)
+	if err != nil {
+		t.Fatalf("Parse(Encode()) error = %v\n%s", err, buf.Bytes())
+	}
+
+	if len(got.Nodes) != len(flow.Nodes) {
+		t.Fatalf("got %d nodes, want %d", len(got.Nodes), len(flow.Nodes))
+	}
+	ids := map[string]string{}
+	for i, n := range flow.Nodes {
+		color := n.Color
+		if color == "" {
+			color = signals.DefaultNodeColor
+		}
+		if g := got.Nodes[i]; g.Label != n.Label || g.Color != color {
+			t.Errorf("node %d = %q/%s, want %q/%s", i, g.Label, g.Color, n.Label, color)
+		}
+		ids[n.ID] = got.Nodes[i].ID
+	}
+	if len(got.Edges) != len(flow.Edges) {
+		t.Fatalf("got %d edges, want %d", len(got.Edges), len(flow.Edges))
+	}
+	for i, e := range flow.Edges {
+		if g := got.Edges[i]; g.Source != ids[e.Source] || g.Target != ids[e.Target] {
+			t.Errorf("edge %d = %s->%s, want %s->%s", i, g.Source, g.Target, ids[e.Source], ids[e.Target])
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package signals
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/yacobolo/datastar-lit-examples/internal/datastar"
+)
+
+// Decode parses and validates a complete signal tree.
+func Decode(data []byte) (Signals, error) {
+	var s Signals
+	if err := json.Unmarshal(data, &s); err != nil {
+		return Signals{}, fmt.Errorf("decode signals: %w", err)
+	}
+	return s, s.Validate()
+}
+
+// ReadFlow reads and validates $flow from a Datastar request.
+func ReadFlow(r *http.Request) (Flow, error) {
+	return readRoot[Flow](r, "flow")
+}
+
+// ReadScene reads and validates $scene from a Datastar request.
+func ReadScene(r *http.Request) (Scene, error) {
+	return readRoot[Scene](r, "scene")
+}
+
+// ReadChart reads and validates $chart from a Datastar request.
+func ReadChart(r *http.Request) (Chart, error) {
+	return readRoot[Chart](r, "chart")
+}
+
+// ReadDoc reads $doc from a Datastar request.
+func ReadDoc(r *http.Request) (Doc, error) {
+	return readRoot[Doc](r, "doc")
+}
+
+// ReadFlowDoc reads $flow and $doc from one Datastar request, whose body
+// can only be read once.
+func ReadFlowDoc(r *http.Request) (Flow, Doc, error) {
+	envelope, err := readEnvelope(r)
+	if err != nil {
+		return Flow{}, Doc{}, err
+	}
+	flow, err := decodeRoot[Flow](envelope, "flow")
+	if err != nil {
+		return Flow{}, Doc{}, err
+	}
+	doc, err := decodeRoot[Doc](envelope, "doc")
+	if err != nil {
+		return Flow{}, Doc{}, err
+	}
+	return flow, doc, nil
+}
+
+// readRoot decodes a single root so a handler is not rejected because some
+// unrelated part of the page is in a bad state.
+func readRoot[T interface{ Validate() error }](r *http.Request, root string) (T, error) {
+	envelope, err := readEnvelope(r)
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	return decodeRoot[T](envelope, root)
+}
+
+func readEnvelope(r *http.Request) (map[string]json.RawMessage, error) {
+	var envelope map[string]json.RawMessage
+	if err := datastar.ReadSignals(r, &envelope); err != nil {
+		return nil, err
+	}
+	return envelope, nil
+}
+
+func decodeRoot[T interface{ Validate() error }](envelope map[string]json.RawMessage, root string) (T, error) {
+	var zero T
+	raw, ok := envelope[root]
+	if !ok {
+		return zero, ValidationError{{Path: root, Message: "is required"}}
+	}
+	var v T
+	if err := json.Unmarshal(raw, &v); err != nil {
+		return zero, fmt.Errorf("decode %s: %w", root, err)
+	}
+	if err := v.Validate(); err != nil {
+		return zero, err
+	}
+	return v, nil
+}
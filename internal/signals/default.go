@@ -0,0 +1,47 @@
+package signals
+
+// Default returns the state the demo page boots with when nothing else is
+// selected.
+func Default() Signals {
+	return Signals{
+		Flow: Flow{
+			Nodes: []FlowNode{
+				{ID: "1", Label: "Input", X: 80, Y: 80, Color: "#6366f1"},
+				{ID: "2", Label: "Process", X: 220, Y: 150, Color: "#a855f7"},
+				{ID: "3", Label: "Output", X: 360, Y: 80, Color: "#10b981"},
+			},
+			Edges: []FlowEdge{
+				{ID: "1", Source: "1", Target: "2"},
+				{ID: "2", Source: "2", Target: "3"},
+			},
+			Config: FlowConfig{NodeRadius: 30, LineWidth: 2, Animate: true},
+		},
+		Scene: Scene{
+			Config: SceneConfig{
+				RotationSpeed: 0.01,
+				Color:         "#6366f1",
+				Wireframe:     false,
+				Shape:         ShapeCube,
+				CameraZ:       5,
+			},
+		},
+		Chart: Chart{
+			Data: []ChartDataPoint{
+				{Name: "Mon", Value: 120},
+				{Name: "Tue", Value: 200},
+				{Name: "Wed", Value: 150},
+				{Name: "Thu", Value: 80},
+				{Name: "Fri", Value: 250},
+				{Name: "Sat", Value: 180},
+				{Name: "Sun", Value: 90},
+			},
+			Config: ChartConfig{
+				Type:       ChartBar,
+				Theme:      ThemeDark,
+				ShowLegend: true,
+				Animate:    true,
+				Color:      "#6366f1",
+			},
+		},
+	}
+}
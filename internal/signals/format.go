@@ -0,0 +1,42 @@
+package signals
+
+import (
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// namedColors are the CSS basic colors, for ParseColor.
+var namedColors = map[string]string{
+	"black": "#000000", "silver": "#c0c0c0", "gray": "#808080", "grey": "#808080",
+	"white": "#ffffff", "maroon": "#800000", "red": "#ff0000", "purple": "#800080",
+	"fuchsia": "#ff00ff", "magenta": "#ff00ff", "green": "#008000", "lime": "#00ff00",
+	"olive": "#808000", "yellow": "#ffff00", "navy": "#000080", "blue": "#0000ff",
+	"teal": "#008080", "aqua": "#00ffff", "cyan": "#00ffff", "orange": "#ffa500",
+}
+
+var shortHex = regexp.MustCompile(`^#[0-9a-fA-F]{3,4}$|^#[0-9a-fA-F]{8}$`)
+
+// ParseColor converts a color from another tool to the #rrggbb form node
+// colors must have. It accepts hex colors of 3, 4, 6 or 8 digits, dropping
+// any alpha, and the CSS basic color names.
+func ParseColor(s string) (string, bool) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	switch {
+	case hexColor.MatchString(s):
+		return s, true
+	case shortHex.MatchString(s) && len(s) == 9:
+		return s[:7], true
+	case shortHex.MatchString(s):
+		return "#" + strings.Repeat(s[1:2], 2) + strings.Repeat(s[2:3], 2) + strings.Repeat(s[3:4], 2), true
+	}
+	c, ok := namedColors[s]
+	return c, ok
+}
+
+// FormatNumber writes a position or size for another tool, with at most
+// three decimals and no exponent.
+func FormatNumber(v float64) string {
+	return strconv.FormatFloat(math.Round(v*1000)/1000, 'f', -1, 64)
+}
@@ -0,0 +1,67 @@
+package signals
+
+import "testing"
+
+func TestParseColor(t *testing.T) {
+	tests := []struct {
+		in, want string
+		ok       bool
+	}{
+		{"#6366f1", "#6366f1", true},
+		{"#6366F1", "#6366f1", true},
+		{"  #abc ", "#aabbcc", true},
+		{"#ABC", "#aabbcc", true},
+		{"#abcd", "#aabbcc", true},
+		{"#6366f180", "#6366f1", true},
+		{"#6366F1FF", "#6366f1", true},
+		{"Red", "#ff0000", true},
+		{"grey", "#808080", true},
+		{"", "", false},
+		{"#ab", "", false},
+		{"#abcde", "", false},
+		{"#6366f1f", "", false},
+		{"#6366f1fff", "", false},
+		{"#ggg", "", false},
+		{"6366f1", "", false},
+		{"rebeccapurple", "", false},
+		{"rgb(255, 0, 0)", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := ParseColor(tt.in)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("ParseColor(%q) = %q, %v, want %q, %v", tt.in, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestNamedColors(t *testing.T) {
+	for name, hex := range namedColors {
+		if !hexColor.MatchString(hex) {
+			t.Errorf("%s is %q, not a #rrggbb color", name, hex)
+		}
+		if got, ok := ParseColor(name); got != hex || !ok {
+			t.Errorf("ParseColor(%q) = %q, %v, want %q, true", name, got, ok, hex)
+		}
+	}
+}
+
+func TestFormatNumber(t *testing.T) {
+	tests := []struct {
+		in   float64
+		want string
+	}{
+		{0, "0"},
+		{80, "80"},
+		{-150, "-150"},
+		{0.5, "0.5"},
+		{1.0 / 3, "0.333"},
+		{2.0 / 3, "0.667"},
+		{1e21, "1000000000000000000000"},
+		{1e-7, "0"},
+	}
+	for _, tt := range tests {
+		if got := FormatNumber(tt.in); got != tt.want {
+			t.Errorf("FormatNumber(%v) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,177 @@
+// Package signals is the Go side of the signal tree the demo page declares
+// in data-signals. It is the source of truth for the props the Lit
+// components receive through data-attr: their TypeScript interfaces and
+// demo/signals.schema.json are generated from these types by gen-signals.
+package signals
+
+//go:generate go run ../../cmd/gen-signals -pkg . -ts ../../demo/components -schema ../../demo/signals.schema.json
+
+// Signals is the complete signal tree of the demo page.
+//
+//signals:schema
+type Signals struct {
+	Flow  Flow  `json:"flow"`
+	Scene Scene `json:"scene"`
+	Chart Chart `json:"chart"`
+	Doc   Doc   `json:"doc"`
+}
+
+// Flow holds the props of <flow-diagram>.
+type Flow struct {
+	Nodes  []FlowNode `json:"nodes"`
+	Edges  []FlowEdge `json:"edges"`
+	Config FlowConfig `json:"config"`
+
+	// Errors is filled in by the server with what flowgraph.Check finds
+	// wrong with the graph. It is never read back from the client.
+	Errors []FlowIssue `json:"errors,omitempty"`
+}
+
+// FlowIssueCode identifies the kind of a FlowIssue.
+type FlowIssueCode string
+
+const (
+	IssueDanglingEdge    FlowIssueCode = "dangling-edge"
+	IssueDuplicateNodeID FlowIssueCode = "duplicate-node-id"
+	IssueDuplicateEdgeID FlowIssueCode = "duplicate-edge-id"
+	IssueEdgeID          FlowIssueCode = "non-numeric-edge-id"
+	IssueSelfLoop        FlowIssueCode = "self-loop"
+	IssueCycle           FlowIssueCode = "cycle"
+	IssueOrphan          FlowIssueCode = "orphan-node"
+)
+
+// Severity says whether a FlowIssue breaks the drawing or is only worth a
+// look.
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+)
+
+// FlowIssue is one problem with the flow graph. Nodes and Edges hold the
+// IDs involved.
+type FlowIssue struct {
+	Code     FlowIssueCode `json:"code"`
+	Severity Severity      `json:"severity"`
+	Message  string        `json:"message"`
+	Nodes    []string      `json:"nodes,omitempty"`
+	Edges    []string      `json:"edges,omitempty"`
+}
+
+// Doc names the saved flow document the page is editing. ID is empty until
+// the diagram is first saved.
+type Doc struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// FlowNode is one circle on the <flow-diagram> canvas.
+//
+//signals:ts flow-diagram
+type FlowNode struct {
+	ID    string  `json:"id"`
+	Label string  `json:"label"`
+	X     float64 `json:"x"`
+	Y     float64 `json:"y"`
+	Color string  `json:"color,omitempty"`
+}
+
+// DefaultNodeColor is the fill <flow-diagram> uses for a FlowNode without
+// a color.
+const DefaultNodeColor = "#6366f1"
+
+// FlowEdge connects two FlowNodes by ID.
+//
+//signals:ts flow-diagram
+type FlowEdge struct {
+	ID     string `json:"id"`
+	Source string `json:"source"`
+	Target string `json:"target"`
+}
+
+// FlowConfig controls how <flow-diagram> draws.
+//
+//signals:ts flow-diagram
+type FlowConfig struct {
+	NodeRadius float64 `json:"nodeRadius"`
+	LineWidth  float64 `json:"lineWidth"`
+	Animate    bool    `json:"animate"`
+}
+
+// Scene holds the props of <scene-viewer>.
+type Scene struct {
+	Config SceneConfig `json:"config"`
+}
+
+// Shape is the mesh rendered by <scene-viewer>.
+type Shape string
+
+const (
+	ShapeCube       Shape = "cube"
+	ShapeSphere     Shape = "sphere"
+	ShapeTorus      Shape = "torus"
+	ShapeOctahedron Shape = "octahedron"
+)
+
+// Shapes lists every valid Shape in the order the page offers them.
+var Shapes = []Shape{ShapeCube, ShapeSphere, ShapeTorus, ShapeOctahedron}
+
+// SceneConfig controls the <scene-viewer> mesh and camera.
+//
+//signals:ts scene-viewer
+type SceneConfig struct {
+	RotationSpeed float64 `json:"rotationSpeed"`
+	Color         string  `json:"color"`
+	Wireframe     bool    `json:"wireframe"`
+	Shape         Shape   `json:"shape"`
+	CameraZ       float64 `json:"cameraZ"`
+}
+
+// Chart holds the props of <data-chart>.
+type Chart struct {
+	Data   []ChartDataPoint `json:"data"`
+	Config ChartConfig      `json:"config"`
+}
+
+// ChartDataPoint is one category of <data-chart>.
+//
+//signals:ts data-chart
+type ChartDataPoint struct {
+	Name  string  `json:"name"`
+	Value float64 `json:"value"`
+}
+
+// ChartType selects the ECharts series type.
+type ChartType string
+
+const (
+	ChartBar  ChartType = "bar"
+	ChartLine ChartType = "line"
+	ChartPie  ChartType = "pie"
+)
+
+// ChartTypes lists every valid ChartType.
+var ChartTypes = []ChartType{ChartBar, ChartLine, ChartPie}
+
+// ChartTheme selects the ECharts theme.
+type ChartTheme string
+
+const (
+	ThemeDark  ChartTheme = "dark"
+	ThemeLight ChartTheme = "light"
+)
+
+// ChartThemes lists every valid ChartTheme.
+var ChartThemes = []ChartTheme{ThemeDark, ThemeLight}
+
+// ChartConfig controls how <data-chart> renders its data.
+//
+//signals:ts data-chart
+type ChartConfig struct {
+	Type       ChartType  `json:"type"`
+	Theme      ChartTheme `json:"theme"`
+	ShowLegend bool       `json:"showLegend"`
+	Animate    bool       `json:"animate"`
+	Color      string     `json:"color"`
+}
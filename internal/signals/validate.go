@@ -0,0 +1,154 @@
+package signals
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// FieldError describes one invalid value. Path uses the same dotted form
+// as a Datastar expression, e.g. flow.nodes[2].color.
+type FieldError struct {
+	Path    string `json:"path"`
+	Message string `json:"message"`
+}
+
+func (e FieldError) Error() string {
+	return e.Path + ": " + e.Message
+}
+
+// ValidationError collects every FieldError found in one pass.
+type ValidationError []FieldError
+
+func (e ValidationError) Error() string {
+	msgs := make([]string, len(e))
+	for i, fe := range e {
+		msgs[i] = fe.Error()
+	}
+	return "invalid signals: " + strings.Join(msgs, "; ")
+}
+
+// The components append a two-digit alpha suffix to colors (color + '40'),
+// so only the six-digit hex form renders correctly.
+var hexColor = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+
+type validator struct {
+	errs ValidationError
+}
+
+func (v *validator) add(path, format string, args ...any) {
+	v.errs = append(v.errs, FieldError{Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+func (v *validator) err() error {
+	if len(v.errs) == 0 {
+		return nil
+	}
+	return v.errs
+}
+
+func (v *validator) color(path, c string, optional bool) {
+	if c == "" && optional {
+		return
+	}
+	if !hexColor.MatchString(c) {
+		v.add(path, "must be a #rrggbb color, got %q", c)
+	}
+}
+
+func (v *validator) required(path, s string) {
+	if s == "" {
+		v.add(path, "is required")
+	}
+}
+
+// Validate checks every root.
+func (s Signals) Validate() error {
+	var v validator
+	s.Flow.validate(&v, "flow")
+	s.Scene.validate(&v, "scene")
+	s.Chart.validate(&v, "chart")
+	s.Doc.validate(&v, "doc")
+	return v.err()
+}
+
+// Validate checks the flow signal.
+func (f Flow) Validate() error {
+	var v validator
+	f.validate(&v, "flow")
+	return v.err()
+}
+
+func (f Flow) validate(v *validator, path string) {
+	for i, n := range f.Nodes {
+		p := fmt.Sprintf("%s.nodes[%d]", path, i)
+		v.required(p+".id", n.ID)
+		v.color(p+".color", n.Color, true)
+	}
+	for i, e := range f.Edges {
+		p := fmt.Sprintf("%s.edges[%d]", path, i)
+		v.required(p+".id", e.ID)
+		v.required(p+".source", e.Source)
+		v.required(p+".target", e.Target)
+	}
+	if f.Config.NodeRadius <= 0 {
+		v.add(path+".config.nodeRadius", "must be positive, got %v", f.Config.NodeRadius)
+	}
+	if f.Config.LineWidth < 0 {
+		v.add(path+".config.lineWidth", "must not be negative, got %v", f.Config.LineWidth)
+	}
+}
+
+// Validate checks the scene signal.
+func (s Scene) Validate() error {
+	var v validator
+	s.validate(&v, "scene")
+	return v.err()
+}
+
+func (s Scene) validate(v *validator, path string) {
+	c := s.Config
+	if !slices.Contains(Shapes, c.Shape) {
+		v.add(path+".config.shape", "must be one of %v, got %q", Shapes, c.Shape)
+	}
+	v.color(path+".config.color", c.Color, false)
+	if c.CameraZ <= 0 {
+		v.add(path+".config.cameraZ", "must be positive, got %v", c.CameraZ)
+	}
+}
+
+// Validate checks the chart signal.
+func (c Chart) Validate() error {
+	var v validator
+	c.validate(&v, "chart")
+	return v.err()
+}
+
+func (c Chart) validate(v *validator, path string) {
+	cfg := c.Config
+	if !slices.Contains(ChartTypes, cfg.Type) {
+		v.add(path+".config.type", "must be one of %v, got %q", ChartTypes, cfg.Type)
+	}
+	if !slices.Contains(ChartThemes, cfg.Theme) {
+		v.add(path+".config.theme", "must be one of %v, got %q", ChartThemes, cfg.Theme)
+	}
+	v.color(path+".config.color", cfg.Color, false)
+}
+
+// Validate checks the doc signal.
+func (d Doc) Validate() error {
+	var v validator
+	d.validate(&v, "doc")
+	return v.err()
+}
+
+// The ID names a file in the flow store, so it is held to the same
+// characters as saved state names.
+var docID = regexp.MustCompile(`^[A-Za-z0-9_-]*$`)
+
+func (d Doc) validate(v *validator, path string) {
+	if !docID.MatchString(d.ID) {
+		v.add(path+".id", "must be letters, digits, - or _, got %q", d.ID)
+	}
+}
@@ -0,0 +1,267 @@
+package signals
+
+import (
+	"errors"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// paths returns the paths of err's FieldErrors, checking each has a
+// message; messages are free to change.
+func paths(t *testing.T, err error) []string {
+	t.Helper()
+	if err == nil {
+		return nil
+	}
+	var ve ValidationError
+	if !errors.As(err, &ve) {
+		t.Fatalf("error %v is not a ValidationError", err)
+	}
+	var got []string
+	for _, fe := range ve {
+		if fe.Message == "" {
+			t.Errorf("%s has no message", fe.Path)
+		}
+		got = append(got, fe.Path)
+	}
+	return got
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(*Signals)
+		want   []string
+	}{
+		{
+			name:   "default",
+			change: func(*Signals) {},
+		},
+		{
+			name: "node without ID",
+			change: func(s *Signals) {
+				s.Flow.Nodes[1].ID = ""
+			},
+			want: []string{"flow.nodes[1].id"},
+		},
+		{
+			name: "node without color",
+			change: func(s *Signals) {
+				s.Flow.Nodes[0].Color = ""
+			},
+		},
+		{
+			name: "node colors",
+			change: func(s *Signals) {
+				s.Flow.Nodes[0].Color = "#abc"
+				s.Flow.Nodes[1].Color = "red"
+				s.Flow.Nodes[2].Color = "#6366f1ff"
+			},
+			want: []string{"flow.nodes[0].color", "flow.nodes[1].color", "flow.nodes[2].color"},
+		},
+		{
+			name: "uppercase hex",
+			change: func(s *Signals) {
+				s.Flow.Nodes[0].Color = "#ABCDEF"
+				s.Scene.Config.Color = "#A855F7"
+			},
+		},
+		{
+			name: "edge without endpoints",
+			change: func(s *Signals) {
+				s.Flow.Edges[1] = FlowEdge{}
+			},
+			want: []string{"flow.edges[1].id", "flow.edges[1].source", "flow.edges[1].target"},
+		},
+		{
+			name: "flow config",
+			change: func(s *Signals) {
+				s.Flow.Config.NodeRadius = 0
+				s.Flow.Config.LineWidth = -1
+			},
+			want: []string{"flow.config.nodeRadius", "flow.config.lineWidth"},
+		},
+		{
+			name: "no lines",
+			change: func(s *Signals) {
+				s.Flow.Config.LineWidth = 0
+			},
+		},
+		{
+			name: "shape",
+			change: func(s *Signals) {
+				s.Scene.Config.Shape = "cone"
+			},
+			want: []string{"scene.config.shape"},
+		},
+		{
+			name: "scene without color",
+			change: func(s *Signals) {
+				s.Scene.Config.Color = ""
+				s.Scene.Config.CameraZ = 0
+			},
+			want: []string{"scene.config.color", "scene.config.cameraZ"},
+		},
+		{
+			name: "chart type and theme",
+			change: func(s *Signals) {
+				s.Chart.Config.Type = "Bar"
+				s.Chart.Config.Theme = ""
+			},
+			want: []string{"chart.config.type", "chart.config.theme"},
+		},
+		{
+			name: "chart without color",
+			change: func(s *Signals) {
+				s.Chart.Config.Color = ""
+			},
+			want: []string{"chart.config.color"},
+		},
+		{
+			name: "doc ID",
+			change: func(s *Signals) {
+				s.Doc.ID = "../state"
+			},
+			want: []string{"doc.id"},
+		},
+		{
+			name: "saved doc",
+			change: func(s *Signals) {
+				s.Doc = Doc{ID: "Pipeline_2-b", Name: "Pipeline / 2"}
+			},
+		},
+		{
+			name: "every root",
+			change: func(s *Signals) {
+				s.Flow.Nodes[2].Color = "blue"
+				s.Scene.Config.Shape = ""
+				s.Chart.Config.Type = "pie chart"
+				s.Doc.ID = "a b"
+			},
+			want: []string{"flow.nodes[2].color", "scene.config.shape", "chart.config.type", "doc.id"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := Default()
+			tt.change(&s)
+			if got := paths(t, s.Validate()); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Validate() paths = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidationError(t *testing.T) {
+	err := ValidationError{
+		{Path: "flow.nodes[0].color", Message: `must be a #rrggbb color, got "red"`},
+		{Path: "doc.id", Message: "must be letters, digits, - or _, got \"a b\""},
+	}
+	want := `invalid signals: flow.nodes[0].color: must be a #rrggbb color, got "red"; doc.id: must be letters, digits, - or _, got "a b"`
+	if got := err.Error(); got != want {
+		t.Errorf("Error() = %s, want %s", got, want)
+	}
+}
+
+func TestDecode(t *testing.T) {
+	tests := []struct {
+		name    string
+		json    string
+		want    []string
+		wantErr bool // an error that is not a ValidationError
+	}{
+		{
+			name: "valid",
+			json: `{"flow":{"nodes":[],"edges":[],"config":{"nodeRadius":30}},
+				"scene":{"config":{"shape":"torus","color":"#112233","cameraZ":5}},
+				"chart":{"config":{"type":"line","theme":"light","color":"#445566"}},
+				"doc":{"id":""}}`,
+		},
+		{
+			// The page holds signals the server does not model, and the
+			// components may add props before the server knows them.
+			name: "unknown fields",
+			json: `{"flow":{"nodes":[{"id":"1","selected":true}],"config":{"nodeRadius":30,"zoom":2}},
+				"scene":{"config":{"shape":"cube","color":"#112233","cameraZ":5}},
+				"chart":{"config":{"type":"bar","theme":"dark","color":"#445566"}},
+				"_editing":true}`,
+		},
+		{
+			name: "invalid",
+			json: `{"flow":{"nodes":[{"id":"","color":"#12"}],"config":{"nodeRadius":30}},
+				"scene":{"config":{"shape":"cube","color":"#112233","cameraZ":5}},
+				"chart":{"config":{"type":"bar","theme":"dark","color":"#445566"}}}`,
+			want: []string{"flow.nodes[0].id", "flow.nodes[0].color"},
+		},
+		{
+			name:    "wrong type",
+			json:    `{"flow":{"nodes":{"id":"1"}}}`,
+			wantErr: true,
+		},
+		{
+			name:    "not JSON",
+			json:    `{"flow":`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Decode([]byte(tt.json))
+			if tt.wantErr {
+				var ve ValidationError
+				if err == nil || errors.As(err, &ve) {
+					t.Fatalf("Decode() error = %v, want a decoding error", err)
+				}
+				return
+			}
+			if got := paths(t, err); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Decode() paths = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadFlow(t *testing.T) {
+	tests := []struct {
+		name    string
+		signals string
+		want    []string
+	}{
+		{
+			name:    "flow only",
+			signals: `{"flow":{"nodes":[{"id":"1","color":"#6366f1"}],"config":{"nodeRadius":30}}}`,
+		},
+		{
+			// Other roots are not read, so they cannot fail the request.
+			name:    "invalid chart",
+			signals: `{"flow":{"config":{"nodeRadius":30}},"chart":{"config":{"type":"area"}}}`,
+		},
+		{
+			name:    "no flow",
+			signals: `{"chart":{}}`,
+			want:    []string{"flow"},
+		},
+		{
+			name:    "invalid flow",
+			signals: `{"flow":{"edges":[{"id":"1","source":"1"}],"config":{"nodeRadius":-1}}}`,
+			want:    []string{"flow.edges[0].target", "flow.config.nodeRadius"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/?datastar="+url.QueryEscape(tt.signals), nil)
+			_, err := ReadFlow(r)
+			if got := paths(t, err); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ReadFlow() paths = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	r := httptest.NewRequest("POST", "/", strings.NewReader(`{"flow":`))
+	if _, err := ReadFlow(r); err == nil {
+		t.Error("ReadFlow() accepted a truncated body")
+	}
+}
@@ -0,0 +1,264 @@
+// Package static serves a restricted view of the document root: only paths
+// matching an allowlist are reachable, dotfiles never are, and every refusal
+// is a plain 404 with a log line saying why. Files carry strong ETags,
+// fingerprinted names are served as immutable, and a .zst or .gz file next
+// to the original, or for fingerprinted names the gzip variant the
+// Versions keep, is sent instead to clients that accept it.
+package static
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/yacobolo/datastar-lit-examples/internal/compress"
+)
+
+// DefaultAllow is what the demo page needs: the page and the build output.
+var DefaultAllow = []string{"index.html", "demo/dist/**"}
+
+// Versions resolves fingerprinted names to the content they were derived
+// from. Such a name changes whenever its content does.
+type Versions interface {
+	Lookup(name string) (data []byte, sum string, ok bool)
+	// Gzipped returns the content of a name Lookup knows compressed with
+	// gzip, or nil if it has no compressed variant.
+	Gzipped(name string) []byte
+}
+
+// Handler serves files from an fs.FS. Pass the FS of an os.Root so that
+// symlinks pointing outside the document root cannot be followed.
+type Handler struct {
+	// Versions, if set, is consulted before the file system. Names it
+	// knows are cached by browsers for a year without revalidation.
+	Versions Versions
+
+	fsys  fs.FS
+	allow []string
+	deny  []string
+
+	mu    sync.Mutex
+	etags map[string]etag
+}
+
+// etag is the cached tag of a file, valid while its size and modification
+// time stay the same.
+type etag struct {
+	size    int64
+	modTime time.Time
+	tag     string
+}
+
+// New returns a Handler serving the files of fsys matched by allow and not
+// matched by deny. Patterns are slash-separated globs as in path.Match,
+// where a ** segment matches any number of directories.
+func New(fsys fs.FS, allow, deny []string) (*Handler, error) {
+	for _, p := range append(append([]string{}, allow...), deny...) {
+		if err := validPattern(p); err != nil {
+			return nil, err
+		}
+	}
+	return &Handler{fsys: fsys, allow: allow, deny: deny, etags: map[string]etag{}}, nil
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	name := strings.TrimPrefix(path.Clean("/"+r.URL.Path), "/")
+	if reason := h.Denied(name); reason != "" {
+		h.notFound(w, r, reason)
+		return
+	}
+
+	if h.Versions != nil {
+		if data, sum, ok := h.Versions.Lookup(name); ok {
+			hdr := w.Header()
+			hdr.Set("Cache-Control", "public, max-age=31536000, immutable")
+			tag := `"` + sum + `"`
+			if gz := h.Versions.Gzipped(name); gz != nil {
+				// The rewritten content differs from the file on disk, so
+				// a .gz next to it would not match.
+				compress.AddVary(hdr)
+				if compress.Negotiate(r, compress.Gzip) != "" {
+					hdr.Set("Content-Encoding", compress.Gzip)
+					data, tag = gz, `"`+sum+`-gzip"`
+				}
+			}
+			hdr.Set("ETag", tag)
+			http.ServeContent(w, r, name, time.Time{}, bytes.NewReader(data))
+			return
+		}
+	}
+
+	file := name
+	if coding := h.precompressed(w, r, name); coding != "" {
+		file = name + precompressedExt[coding]
+		w.Header().Set("Content-Encoding", coding)
+	}
+
+	f, err := h.fsys.Open(file)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			http.NotFound(w, r)
+		} else {
+			// os.Root reports symlinks leaving the root here.
+			h.notFound(w, r, err.Error())
+		}
+		return
+	}
+	defer f.Close()
+
+	fi, err := f.Stat()
+	if err != nil {
+		h.notFound(w, r, err.Error())
+		return
+	}
+	if fi.IsDir() {
+		h.notFound(w, r, "directory listing")
+		return
+	}
+	rs, ok := f.(io.ReadSeeker)
+	if !ok {
+		h.notFound(w, r, "file is not seekable")
+		return
+	}
+	tag, err := h.etag(file, fi, rs)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "static: read failed", "path", name, "err", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	// Browsers may keep the file but must check the tag before using it.
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("ETag", tag)
+	http.ServeContent(w, r, name, fi.ModTime(), rs)
+}
+
+// precompressedExt maps codings to the suffix of their precompressed files,
+// in order of preference.
+var precompressedExt = map[string]string{compress.Zstd: ".zst", compress.Gzip: ".gz"}
+
+// precompressed returns the coding of the precompressed variant of name to
+// send, or "" to send name itself. Responses for names that have variants
+// vary on Accept-Encoding whichever is sent.
+func (h *Handler) precompressed(w http.ResponseWriter, r *http.Request, name string) string {
+	var offered []string
+	for _, coding := range []string{compress.Zstd, compress.Gzip} {
+		if fi, err := fs.Stat(h.fsys, name+precompressedExt[coding]); err == nil && fi.Mode().IsRegular() {
+			offered = append(offered, coding)
+		}
+	}
+	if len(offered) == 0 {
+		return ""
+	}
+	compress.AddVary(w.Header())
+	return compress.Negotiate(r, offered...)
+}
+
+// etag returns the strong ETag of the file at name, hashing its content
+// the first time and after it changes. rs is left at the start.
+func (h *Handler) etag(name string, fi fs.FileInfo, rs io.ReadSeeker) (string, error) {
+	h.mu.Lock()
+	e, ok := h.etags[name]
+	h.mu.Unlock()
+	if ok && e.size == fi.Size() && e.modTime.Equal(fi.ModTime()) {
+		return e.tag, nil
+	}
+
+	sum := sha256.New()
+	if _, err := io.Copy(sum, rs); err != nil {
+		return "", err
+	}
+	if _, err := rs.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	e = etag{size: fi.Size(), modTime: fi.ModTime(), tag: `"` + hex.EncodeToString(sum.Sum(nil)) + `"`}
+	h.mu.Lock()
+	h.etags[name] = e
+	h.mu.Unlock()
+	return e.tag, nil
+}
+
+// Denied reports why name may not be served, or "" if it may.
+func (h *Handler) Denied(name string) string {
+	if name == "" {
+		return "directory listing"
+	}
+	for _, seg := range strings.Split(name, "/") {
+		if strings.HasPrefix(seg, ".") {
+			return "dotfile"
+		}
+	}
+	for _, p := range h.deny {
+		if Match(p, name) {
+			return fmt.Sprintf("matches deny pattern %q", p)
+		}
+	}
+	for _, p := range h.allow {
+		if Match(p, name) {
+			return ""
+		}
+	}
+	return "not in allowlist"
+}
+
+func (h *Handler) notFound(w http.ResponseWriter, r *http.Request, reason string) {
+	slog.InfoContext(r.Context(), "static: denied", "method", r.Method, "path", r.URL.Path, "reason", reason)
+	http.NotFound(w, r)
+}
+
+// Match reports whether the slash-separated name matches pattern. Segments
+// are matched with path.Match; a ** segment matches zero or more segments.
+func Match(pattern, name string) bool {
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+func matchSegments(pat, name []string) bool {
+	for len(pat) > 0 {
+		if pat[0] == "**" {
+			for i := len(name); i >= 0; i-- {
+				if matchSegments(pat[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pat[0], name[0]); !ok {
+			return false
+		}
+		pat, name = pat[1:], name[1:]
+	}
+	return len(name) == 0
+}
+
+func validPattern(p string) error {
+	if p == "" || strings.HasPrefix(p, "/") {
+		return fmt.Errorf("static: pattern %q must be a relative path", p)
+	}
+	for _, seg := range strings.Split(p, "/") {
+		if seg == "**" {
+			continue
+		}
+		if _, err := path.Match(seg, ""); err != nil {
+			return fmt.Errorf("static: bad pattern %q: %w", p, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,149 @@
+// Package svg draws flow diagrams as SVG, the way <flow-diagram> paints
+// its canvas, for pages and tools that cannot run the component.
+package svg
+
+import (
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/yacobolo/datastar-lit-examples/internal/signals"
+)
+
+// Width and Height are the size of the component's canvas at its default
+// height and the usual column width. Diagrams that reach past it get a
+// larger picture rather than being cut off.
+const (
+	Width  = 480
+	Height = 300
+)
+
+const (
+	background  = "#1a1a2e" // the component's --surface-2 fallback
+	edgeColor   = "#6366f1" // rgba(99, 102, 241, 0.6)
+	edgeOpacity = 0.6
+	glowOpacity = 0x40 / 255.0 // the 40 appended to the color
+	bend        = 30           // how far above the midpoint the control point lies
+)
+
+// Encode writes f as a standalone SVG image. Edges are quadratic curves
+// through a control point 30 units above their midpoint, drawn first; each
+// node then gets a radial glow of 1.5 times its radius, a circle with a
+// white outline and its label centered on it, in the order of f's nodes.
+// The radius and edge width come from f's config, falling back to the
+// component's defaults when zero. The moving dots of an animated diagram
+// are left out.
+func Encode(w io.Writer, f signals.Flow) error {
+	radius, lineWidth := f.Config.NodeRadius, f.Config.LineWidth
+	if radius <= 0 {
+		radius = 30
+	}
+	if lineWidth <= 0 {
+		lineWidth = 2
+	}
+
+	// Edges join the first node with each ID, as on the canvas.
+	byID := map[string]signals.FlowNode{}
+	for _, n := range f.Nodes {
+		if _, ok := byID[n.ID]; !ok {
+			byID[n.ID] = n
+		}
+	}
+	type curve struct{ sx, sy, cx, cy, tx, ty float64 }
+	var curves []curve
+	for _, e := range f.Edges {
+		source, ok1 := byID[e.Source]
+		target, ok2 := byID[e.Target]
+		if !ok1 || !ok2 {
+			continue
+		}
+		curves = append(curves, curve{
+			source.X, source.Y,
+			(source.X + target.X) / 2, (source.Y+target.Y)/2 - bend,
+			target.X, target.Y,
+		})
+	}
+
+	colors := make([]string, len(f.Nodes))
+	glows := map[string]int{}
+	var glowColors []string
+	for i, n := range f.Nodes {
+		c, ok := signals.ParseColor(n.Color)
+		if !ok {
+			c = signals.DefaultNodeColor
+		}
+		colors[i] = c
+		if _, ok := glows[c]; !ok {
+			glows[c] = len(glowColors) + 1
+			glowColors = append(glowColors, c)
+		}
+	}
+
+	b := bounds{0, 0, Width, Height}
+	for _, n := range f.Nodes {
+		b.add(n.X-1.5*radius, n.Y-1.5*radius)
+		b.add(n.X+1.5*radius, n.Y+1.5*radius)
+	}
+	for _, c := range curves {
+		// The x of the curve runs straight from source to target, so only
+		// its highest or lowest y can lie beyond the end points.
+		y := c.sy
+		if d := c.sy - 2*c.cy + c.ty; d != 0 {
+			t := math.Max(0, math.Min(1, (c.sy-c.cy)/d))
+			y = (1-t)*(1-t)*c.sy + 2*(1-t)*t*c.cy + t*t*c.ty
+		}
+		b.add(c.sx, y-lineWidth/2)
+		b.add(c.sx, y+lineWidth/2)
+	}
+	width, height := b.maxX-b.minX, b.maxY-b.minY
+
+	num := signals.FormatNumber
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, `<svg xmlns="http://www.w3.org/2000/svg" width="%s" height="%s" viewBox="%s %s %s %s" role="img" aria-label="Flow diagram">`+"\n",
+		num(width), num(height), num(b.minX), num(b.minY), num(width), num(height))
+	if len(glowColors) > 0 {
+		fmt.Fprintln(bw, "  <defs>")
+		for i, c := range glowColors {
+			fmt.Fprintf(bw, `    <radialGradient id="flow-glow-%d"><stop offset="0" stop-color="%s"/><stop offset="0.6" stop-color="%s" stop-opacity="%s"/><stop offset="1" stop-color="%s" stop-opacity="0"/></radialGradient>`+"\n",
+				i+1, c, c, num(glowOpacity), c)
+		}
+		fmt.Fprintln(bw, "  </defs>")
+	}
+	fmt.Fprintf(bw, `  <rect x="%s" y="%s" width="%s" height="%s" rx="8" fill="%s"/>`+"\n",
+		num(b.minX), num(b.minY), num(width), num(height), background)
+	if len(curves) > 0 {
+		fmt.Fprintf(bw, `  <g fill="none" stroke="%s" stroke-opacity="%s" stroke-width="%s">`+"\n",
+			edgeColor, num(edgeOpacity), num(lineWidth))
+		for _, c := range curves {
+			fmt.Fprintf(bw, `    <path d="M%s %s Q%s %s %s %s"/>`+"\n",
+				num(c.sx), num(c.sy), num(c.cx), num(c.cy), num(c.tx), num(c.ty))
+		}
+		fmt.Fprintln(bw, "  </g>")
+	}
+	if len(f.Nodes) > 0 {
+		fmt.Fprintln(bw, `  <g font-family="system-ui, sans-serif" font-size="12" text-anchor="middle" dominant-baseline="central">`)
+		for i, n := range f.Nodes {
+			x, y := num(n.X), num(n.Y)
+			fmt.Fprintf(bw, `    <circle cx="%s" cy="%s" r="%s" fill="url(#flow-glow-%d)"/>`+"\n",
+				x, y, num(1.5*radius), glows[colors[i]])
+			fmt.Fprintf(bw, `    <circle cx="%s" cy="%s" r="%s" fill="%s" stroke="#ffffff" stroke-width="2"/>`+"\n",
+				x, y, num(radius), colors[i])
+			fmt.Fprintf(bw, `    <text x="%s" y="%s" fill="#ffffff">`, x, y)
+			xml.EscapeText(bw, []byte(n.Label))
+			fmt.Fprintln(bw, "</text>")
+		}
+		fmt.Fprintln(bw, "  </g>")
+	}
+	fmt.Fprintln(bw, "</svg>")
+	return bw.Flush()
+}
+
+// bounds is the area the picture has to cover.
+type bounds struct{ minX, minY, maxX, maxY float64 }
+
+func (b *bounds) add(x, y float64) {
+	b.minX, b.maxX = math.Min(b.minX, x), math.Max(b.maxX, x)
+	b.minY, b.maxY = math.Min(b.minY, y), math.Max(b.maxY, y)
+}
@@ -0,0 +1,235 @@
+package zstd
+
+import (
+	"math"
+	"math/bits"
+)
+
+// bitWriter packs the backward bitstreams of FSE and Huffman coding: bits
+// are added from the least significant end up, and the reader starts from
+// the marker bit close adds. The FSE table descriptions are forward
+// streams of the same layout without the marker.
+type bitWriter struct {
+	out []byte
+	acc uint64
+	n   uint
+}
+
+func (b *bitWriter) add(v uint32, n uint8) {
+	b.acc |= uint64(v&(1<<n-1)) << b.n
+	b.n += uint(n)
+	for b.n >= 8 {
+		b.out = append(b.out, byte(b.acc))
+		b.acc >>= 8
+		b.n -= 8
+	}
+}
+
+// pad completes the last byte with zeros.
+func (b *bitWriter) pad() []byte {
+	if b.n > 0 {
+		b.out = append(b.out, byte(b.acc))
+	}
+	b.acc, b.n = 0, 0
+	return b.out
+}
+
+func (b *bitWriter) close() []byte {
+	b.add(1, 1)
+	return b.pad()
+}
+
+// fseTable encodes symbols with one finite state entropy distribution. A
+// table with log 0 stands for a single symbol, which costs no bits.
+type fseTable struct {
+	log    uint8
+	norm   []int16  // the distribution, -1 for a probability below one
+	states []uint32 // next state, by symbol and the state's top bits
+	syms   []fseSymbol
+}
+
+type fseSymbol struct {
+	deltaBits  uint32 // turns a state into the number of bits it emits
+	deltaState int32  // where the symbol's states start in states, less its count
+}
+
+// newFSETable builds the encoding table for a normalized distribution,
+// spreading the symbols over the states as the decoder does.
+func newFSETable(log uint8, norm []int16) *fseTable {
+	size := 1 << log
+	spread := make([]uint8, size)
+	high := size - 1
+	for s, p := range norm {
+		if p == -1 {
+			spread[high] = uint8(s)
+			high--
+		}
+	}
+	pos, step := 0, size>>1+size>>3+3
+	for s, p := range norm {
+		for range max(p, 0) {
+			spread[pos] = uint8(s)
+			for pos = (pos + step) & (size - 1); pos > high; pos = (pos + step) & (size - 1) {
+			}
+		}
+	}
+
+	t := &fseTable{log: log, norm: norm, states: make([]uint32, size), syms: make([]fseSymbol, len(norm))}
+	next := make([]int, len(norm))
+	total := 0
+	for s, p := range norm {
+		next[s] = total
+		switch {
+		case p == -1 || p == 1:
+			t.syms[s] = fseSymbol{uint32(log)<<16 - uint32(size), int32(total - 1)}
+			total++
+		case p > 1:
+			out := uint32(log) - uint32(bits.Len32(uint32(p-1))-1)
+			t.syms[s] = fseSymbol{out<<16 - uint32(p)<<out, int32(total - int(p))}
+			total += int(p)
+		}
+	}
+	for u, s := range spread {
+		t.states[next[s]] = uint32(size + u)
+		next[s]++
+	}
+	return t
+}
+
+// cost estimates the bits count takes to code with t, or reports false if
+// t lacks one of the symbols.
+func (t *fseTable) cost(count []int) (float64, bool) {
+	if t.log == 0 {
+		return 0, true
+	}
+	bits := 0.0
+	for s, c := range count {
+		if c == 0 {
+			continue
+		}
+		if s >= len(t.norm) || t.norm[s] == 0 {
+			return 0, false
+		}
+		bits += float64(c) * (float64(t.log) - math.Log2(float64(max(t.norm[s], 1))))
+	}
+	return bits, true
+}
+
+type fseState struct {
+	t     *fseTable
+	state uint32
+}
+
+// init starts with the state that decodes to the last symbol, which costs
+// no bits.
+func (f *fseState) init(t *fseTable, sym uint8) {
+	f.t = t
+	if t.log == 0 {
+		return
+	}
+	s := t.syms[sym]
+	out := (s.deltaBits + 1<<15) >> 16
+	v := out<<16 - s.deltaBits
+	f.state = t.states[int32(v>>out)+s.deltaState]
+}
+
+func (f *fseState) encode(b *bitWriter, sym uint8) {
+	if f.t.log == 0 {
+		return
+	}
+	s := f.t.syms[sym]
+	out := (f.state + s.deltaBits) >> 16
+	b.add(f.state, uint8(out))
+	f.state = f.t.states[int32(f.state>>out)+s.deltaState]
+}
+
+// flush writes the final state, which the decoder reads first.
+func (f *fseState) flush(b *bitWriter) {
+	b.add(f.state, f.t.log)
+}
+
+// tableLog picks the accuracy for coding n symbols up to maxSym, as the
+// reference encoder does: no more than maxLog and the input calls for,
+// enough to give every symbol a state.
+func tableLog(maxLog uint8, n, maxSym int) uint8 {
+	log := min(int(maxLog), bits.Len(uint(n-1))-3)
+	log = max(log, min(bits.Len(uint(n)), bits.Len(uint(maxSym))+1))
+	return uint8(max(log, 5))
+}
+
+// normalize scales count, which adds up to total, to a distribution over
+// 1<<log states in which every symbol present gets at least one.
+func normalize(count []int, total int, log uint8) []int16 {
+	size := 1 << log
+	norm := make([]int16, len(count))
+	sum, largest := 0, 0
+	for s, c := range count {
+		if c == 0 {
+			continue
+		}
+		n := max(1, (c*size+total/2)/total)
+		norm[s] = int16(n)
+		sum += n
+		if n > int(norm[largest]) {
+			largest = s
+		}
+	}
+	// Rounding leaves the sum a few states off; the largest symbols absorb
+	// the difference.
+	norm[largest] += int16(size - sum)
+	for norm[largest] < 1 {
+		norm[largest] = 1
+		sum = 0
+		for s, n := range norm {
+			sum += int(n)
+			if n > norm[largest] {
+				largest = s
+			}
+		}
+		norm[largest] += int16(size - sum)
+	}
+	return norm
+}
+
+// writeNCount appends the description of t's distribution, RFC 8878
+// 4.1.1.
+func writeNCount(dst []byte, t *fseTable) []byte {
+	bw := bitWriter{out: dst}
+	bw.add(uint32(t.log-5), 4)
+	remaining := 1<<t.log + 1
+	threshold := 1 << t.log
+	nbits := t.log + 1
+	zeros := false
+	for s := 0; s < len(t.norm) && remaining > 1; s++ {
+		if zeros {
+			// A zero is followed by the number of zeros after it, three
+			// at a time.
+			start := s
+			for t.norm[s] == 0 {
+				s++
+			}
+			for ; s-start >= 3; start += 3 {
+				bw.add(3, 2)
+			}
+			bw.add(uint32(s-start), 2)
+		}
+		n := int(t.norm[s])
+		limit := 2*threshold - 1 - remaining
+		remaining -= max(n, -n)
+		v := n + 1
+		if v >= threshold {
+			v += limit
+		}
+		if v < limit {
+			bw.add(uint32(v), nbits-1)
+		} else {
+			bw.add(uint32(v), nbits)
+		}
+		zeros = n == 0
+		for remaining < threshold {
+			nbits--
+			threshold >>= 1
+		}
+	}
+	return bw.pad()
+}
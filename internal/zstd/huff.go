@@ -0,0 +1,237 @@
+package zstd
+
+import "slices"
+
+const (
+	huffMaxBits = 11 // the longest code the format allows
+	huffMinLits = 64 // below this the tree costs more than it saves
+)
+
+// huffEncoder Huffman-codes the literals of a block.
+type huffEncoder struct {
+	count   [256]int
+	codes   [256]huffCode
+	weights []uint8
+}
+
+type huffCode struct {
+	code uint32
+	len  uint8
+}
+
+// literals appends lits as a Huffman-coded literals section and reports
+// whether that is smaller than storing them raw.
+func (e *huffEncoder) literals(dst, lits []byte) ([]byte, bool) {
+	if len(lits) < huffMinLits {
+		return dst, false
+	}
+	clear(e.count[:])
+	maxSym := 0
+	for _, c := range lits {
+		e.count[c]++
+		maxSym = max(maxSym, int(c))
+	}
+	if e.count[maxSym] == len(lits) {
+		return dst, false
+	}
+	lens := huffLengths(e.count[:maxSym+1])
+	e.assign(lens)
+
+	start := len(dst)
+	var format, headerLen int
+	switch n := len(lits); {
+	case n < 1<<10:
+		format, headerLen = 0, 3
+	case n < 1<<14:
+		format, headerLen = 2, 4
+	default:
+		format, headerLen = 3, 5
+	}
+	dst = append(dst, make([]byte, headerLen)...)
+
+	// Weights of every symbol but the last, which the decoder works out.
+	longest := slices.Max(lens)
+	e.weights = e.weights[:0]
+	for _, n := range lens[:maxSym] {
+		if n > 0 {
+			n = longest + 1 - n
+		}
+		e.weights = append(e.weights, n)
+	}
+	if tree, ok := compressWeights(dst, e.weights); ok && (maxSym > 128 || len(tree)-len(dst) < 1+(maxSym+1)/2) {
+		dst = tree
+	} else if maxSym <= 128 {
+		dst = append(dst, byte(127+maxSym))
+		for i := 0; i < maxSym; i += 2 {
+			b := e.weights[i] << 4
+			if i+1 < maxSym {
+				b |= e.weights[i+1]
+			}
+			dst = append(dst, b)
+		}
+	} else {
+		return dst[:start], false
+	}
+
+	if format == 0 {
+		dst = e.stream(dst, lits)
+	} else {
+		jump := len(dst)
+		dst = append(dst, make([]byte, 6)...)
+		seg := (len(lits) + 3) / 4
+		for i := range 4 {
+			from := len(dst)
+			dst = e.stream(dst, lits[min(i*seg, len(lits)):min((i+1)*seg, len(lits))])
+			if i < 3 {
+				size := len(dst) - from
+				if size > 0xffff {
+					return dst[:start], false
+				}
+				dst[jump+2*i], dst[jump+2*i+1] = byte(size), byte(size>>8)
+			}
+		}
+	}
+
+	size := len(dst) - start - headerLen
+	if size+headerLen >= len(lits)+3 {
+		return dst[:start], false
+	}
+	v := uint64(literalsCompressed) | uint64(format)<<2 | uint64(len(lits))<<4
+	switch format {
+	case 0:
+		v |= uint64(size) << 14
+	case 2:
+		v |= uint64(size) << 18
+	case 3:
+		v |= uint64(size) << 22
+	}
+	for i := range headerLen {
+		dst[start+i] = byte(v >> (8 * i))
+	}
+	return dst, true
+}
+
+// compressWeights appends the weights of a Huffman tree compressed with
+// FSE, two interleaved states sharing one table, and reports whether they
+// can be: the description has to fit in 127 bytes and needs two different
+// weights at least.
+func compressWeights(dst, weights []uint8) ([]byte, bool) {
+	var counts [huffMaxBits + 2]int
+	maxW := 0
+	for _, w := range weights {
+		counts[w]++
+		maxW = max(maxW, int(w))
+	}
+	if counts[maxW] == len(weights) {
+		return dst, false
+	}
+	log := tableLog(6, len(weights), maxW)
+	t := newFSETable(log, normalize(counts[:maxW+1], len(weights), log))
+
+	start := len(dst)
+	dst = writeNCount(append(dst, 0), t)
+	bw := bitWriter{out: dst}
+	var st [2]fseState // even and odd weights
+	for i := len(weights) - 1; i >= 0; i-- {
+		if i >= len(weights)-2 {
+			st[i%2].init(t, weights[i])
+		} else {
+			st[i%2].encode(&bw, weights[i])
+		}
+	}
+	st[1].flush(&bw)
+	st[0].flush(&bw)
+	dst = bw.close()
+	if size := len(dst) - start - 1; size < 128 {
+		dst[start] = byte(size)
+		return dst, true
+	}
+	return dst[:start], false
+}
+
+// stream appends one Huffman bitstream of lits, last byte first so the
+// decoder, reading backwards, gets them in order.
+func (e *huffEncoder) stream(dst, lits []byte) []byte {
+	bw := bitWriter{out: dst}
+	for i := len(lits) - 1; i >= 0; i-- {
+		c := e.codes[lits[i]]
+		bw.add(c.code, c.len)
+	}
+	return bw.close()
+}
+
+// assign gives each symbol its canonical code: the table of 1<<longest
+// entries is filled from the longest codes to the shortest, each length
+// in symbol order, and a code is the top bits of its first entry.
+func (e *huffEncoder) assign(lens []uint8) {
+	longest := slices.Max(lens)
+	next := uint32(0)
+	for l := longest; l > 0; l-- {
+		for s, n := range lens {
+			if n == l {
+				e.codes[s] = huffCode{next >> (longest - l), l}
+				next += 1 << (longest - l)
+			}
+		}
+	}
+}
+
+// huffLengths returns the code length of each symbol with a count, none
+// longer than huffMaxBits. Counts are halved until the tree is shallow
+// enough, which flattens the rarest symbols first.
+func huffLengths(count []int) []uint8 {
+	count = slices.Clone(count)
+	for {
+		lens := huffman(count)
+		if slices.Max(lens) <= huffMaxBits {
+			return lens
+		}
+		for s, c := range count {
+			count[s] = (c + 1) / 2
+		}
+	}
+}
+
+// huffman returns the depth of each symbol in a Huffman tree for count,
+// built by merging the two lightest nodes from a queue of the sorted
+// leaves and a queue of the merged nodes.
+func huffman(count []int) []uint8 {
+	var syms []int
+	for s, c := range count {
+		if c > 0 {
+			syms = append(syms, s)
+		}
+	}
+	slices.SortStableFunc(syms, func(a, b int) int { return count[a] - count[b] })
+
+	type node struct{ weight, parent int }
+	nodes := make([]node, len(syms), 2*len(syms)-1)
+	for i, s := range syms {
+		nodes[i] = node{count[s], -1}
+	}
+	leaf, merged := 0, len(syms)
+	lightest := func() int {
+		if leaf < len(syms) && (merged == len(nodes) || nodes[leaf].weight <= nodes[merged].weight) {
+			leaf++
+			return leaf - 1
+		}
+		merged++
+		return merged - 1
+	}
+	for len(nodes) < cap(nodes) {
+		a := lightest()
+		b := lightest()
+		nodes = append(nodes, node{nodes[a].weight + nodes[b].weight, -1})
+		nodes[a].parent, nodes[b].parent = len(nodes)-1, len(nodes)-1
+	}
+
+	depth := make([]uint8, len(nodes))
+	for i := len(nodes) - 2; i >= 0; i-- {
+		depth[i] = depth[nodes[i].parent] + 1
+	}
+	lens := make([]uint8, len(count))
+	for i, s := range syms {
+		lens[s] = depth[i]
+	}
+	return lens
+}
@@ -0,0 +1,180 @@
+package zstd
+
+import "math/bits"
+
+// A sequence copies lit literals, then match bytes from the offset that
+// offset codes: 1 to 3 pick a repeat offset, anything above is the
+// distance plus 3.
+type sequence struct {
+	lit, offset, match uint32
+}
+
+// code is a sequence code's baseline and the number of extra bits after
+// it.
+type code struct {
+	base uint32
+	bits uint8
+}
+
+// llCodes and mlCodes are the literal and match length codes, RFC 8878
+// 3.1.1.3.2.1.1. Lengths below the first code with extra bits map to
+// themselves.
+var (
+	llCodes = []code{
+		{0, 0}, {1, 0}, {2, 0}, {3, 0}, {4, 0}, {5, 0}, {6, 0}, {7, 0},
+		{8, 0}, {9, 0}, {10, 0}, {11, 0}, {12, 0}, {13, 0}, {14, 0}, {15, 0},
+		{16, 1}, {18, 1}, {20, 1}, {22, 1}, {24, 2}, {28, 2}, {32, 3}, {40, 3},
+		{48, 4}, {64, 6}, {128, 7}, {256, 8}, {512, 9}, {1024, 10}, {2048, 11}, {4096, 12},
+		{8192, 13}, {16384, 14}, {32768, 15}, {65536, 16},
+	}
+	mlCodes = []code{
+		{3, 0}, {4, 0}, {5, 0}, {6, 0}, {7, 0}, {8, 0}, {9, 0}, {10, 0},
+		{11, 0}, {12, 0}, {13, 0}, {14, 0}, {15, 0}, {16, 0}, {17, 0}, {18, 0},
+		{19, 0}, {20, 0}, {21, 0}, {22, 0}, {23, 0}, {24, 0}, {25, 0}, {26, 0},
+		{27, 0}, {28, 0}, {29, 0}, {30, 0}, {31, 0}, {32, 0}, {33, 0}, {34, 0},
+		{35, 1}, {37, 1}, {39, 1}, {41, 1}, {43, 2}, {47, 2}, {51, 3}, {59, 3},
+		{67, 4}, {83, 4}, {99, 5}, {131, 7}, {259, 8}, {515, 9}, {1027, 10}, {2051, 11},
+		{4099, 12}, {8195, 13}, {16387, 14}, {32771, 15}, {65539, 16},
+	}
+)
+
+// Past these lengths every code covers a power of two.
+var llSmall, mlSmall = lookup(llCodes, 64), lookup(mlCodes, 128+3)
+
+func lookup(codes []code, n int) []uint8 {
+	t := make([]uint8, n)
+	c := 0
+	for v := range t {
+		for c+1 < len(codes) && codes[c+1].base <= uint32(v) {
+			c++
+		}
+		t[v] = uint8(c)
+	}
+	return t
+}
+
+func llCode(lit uint32) uint8 {
+	if lit < uint32(len(llSmall)) {
+		return llSmall[lit]
+	}
+	return uint8(bits.Len32(lit)-1) + 19
+}
+
+func mlCode(match uint32) uint8 {
+	if match < uint32(len(mlSmall)) {
+		return mlSmall[match]
+	}
+	return uint8(bits.Len32(match-3)-1) + 36
+}
+
+// ofCode is the code of an offset, whose extra bits are the offset below
+// its top bit.
+func ofCode(offset uint32) uint8 {
+	return uint8(bits.Len32(offset) - 1)
+}
+
+// The predefined distributions, RFC 8878 3.1.1.3.2.2. A -1 is a
+// probability below one, which still gets a state.
+var (
+	llPredefined = newFSETable(6, []int16{
+		4, 3, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 1, 1, 1,
+		2, 2, 2, 2, 2, 2, 2, 2, 2, 3, 2, 1, 1, 1, 1, 1,
+		-1, -1, -1, -1,
+	})
+	mlPredefined = newFSETable(6, []int16{
+		1, 4, 3, 2, 2, 2, 2, 2, 2, 1, 1, 1, 1, 1, 1, 1,
+		1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1,
+		1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, -1, -1,
+		-1, -1, -1, -1, -1,
+	})
+	ofPredefined = newFSETable(5, []int16{
+		1, 1, 1, 1, 1, 1, 2, 2, 2, 1, 1, 1, 1, 1, 1, 1,
+		1, 1, 1, 1, 1, 1, 1, 1, -1, -1, -1, -1, -1,
+	})
+)
+
+// Symbol compression modes.
+const (
+	modePredefined = 0
+	modeRLE        = 1
+	modeCompressed = 2
+)
+
+// chooseTable picks the cheapest way to code the symbols in codes: the
+// predefined table, a single repeated symbol, or a table of their own
+// that is described in the block.
+func chooseTable(dst []byte, codes []uint8, predefined *fseTable, maxLog uint8) ([]byte, byte, *fseTable) {
+	var counts [64]int
+	maxSym := 0
+	for _, c := range codes {
+		counts[c]++
+		maxSym = max(maxSym, int(c))
+	}
+	if counts[maxSym] == len(codes) {
+		return append(dst, byte(maxSym)), modeRLE, &fseTable{}
+	}
+	count := counts[:maxSym+1]
+
+	log := tableLog(maxLog, len(codes), maxSym)
+	own := newFSETable(log, normalize(count, len(codes), log))
+	desc := writeNCount(dst, own)
+	ownCost, _ := own.cost(count)
+	ownCost += float64(8 * (len(desc) - len(dst)))
+	if cost, ok := predefined.cost(count); ok && cost <= ownCost {
+		return dst, modePredefined, predefined
+	}
+	return desc, modeCompressed, own
+}
+
+// sequences appends the sequences section. The bitstream is read
+// backwards, so the last sequence goes first.
+func (z *Writer) sequences(dst []byte) []byte {
+	seqs := z.seqs
+	switch n := len(seqs); {
+	case n < 0x80:
+		dst = append(dst, byte(n))
+	case n < 0x7f00:
+		dst = append(dst, byte(n>>8)+0x80, byte(n))
+	default:
+		dst = append(dst, 0xff, byte(n-0x7f00), byte((n-0x7f00)>>8))
+	}
+	if len(seqs) == 0 {
+		return dst
+	}
+
+	z.llc, z.mlc, z.ofc = z.llc[:0], z.mlc[:0], z.ofc[:0]
+	for _, s := range seqs {
+		z.llc = append(z.llc, llCode(s.lit))
+		z.mlc = append(z.mlc, mlCode(s.match))
+		z.ofc = append(z.ofc, ofCode(s.offset))
+	}
+	modes := len(dst)
+	dst = append(dst, 0)
+	dst, llMode, llTable := chooseTable(dst, z.llc, llPredefined, 9)
+	dst, ofMode, ofTable := chooseTable(dst, z.ofc, ofPredefined, 8)
+	dst, mlMode, mlTable := chooseTable(dst, z.mlc, mlPredefined, 9)
+	dst[modes] = llMode<<6 | ofMode<<4 | mlMode<<2
+
+	bw := bitWriter{out: dst}
+	var ll, ml, of fseState
+	for i := len(seqs) - 1; i >= 0; i-- {
+		s := seqs[i]
+		llc, mlc, ofc := z.llc[i], z.mlc[i], z.ofc[i]
+		if i == len(seqs)-1 {
+			ll.init(llTable, llc)
+			ml.init(mlTable, mlc)
+			of.init(ofTable, ofc)
+		} else {
+			of.encode(&bw, ofc)
+			ml.encode(&bw, mlc)
+			ll.encode(&bw, llc)
+		}
+		bw.add(s.lit-llCodes[llc].base, llCodes[llc].bits)
+		bw.add(s.match-mlCodes[mlc].base, mlCodes[mlc].bits)
+		bw.add(s.offset, ofc)
+	}
+	ml.flush(&bw)
+	of.flush(&bw)
+	ll.flush(&bw)
+	return bw.close()
+}
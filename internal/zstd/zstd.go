@@ -0,0 +1,305 @@
+// Package zstd compresses data in the Zstandard format (RFC 8878), for
+// responses sent with Content-Encoding: zstd. The Go standard library can
+// only decode it. The encoder is small and fast rather than thorough: one
+// pass over a hash table finds the matches, looking a byte ahead before
+// taking one, and each block codes its sequences and literals with
+// whichever tables come out smallest, which puts its output in the range
+// of gzip's default level.
+package zstd
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+const (
+	windowLog = 17
+	window    = 1 << windowLog // also the largest block the format allows
+	minMatch  = 4
+	hashLog   = 15
+)
+
+// header starts every frame: the magic number, a descriptor with no
+// content size, checksum or dictionary, and the window size.
+var header = []byte{0x28, 0xb5, 0x2f, 0xfd, 0x00, (windowLog - 10) << 3}
+
+const (
+	blockRaw        = 0
+	blockCompressed = 2
+)
+
+var errClosed = errors.New("zstd: write after close")
+
+// A Writer compresses what is written to it into a single frame. Flush
+// ends the current block so the receiver can decode everything written so
+// far, and later blocks still refer back to earlier ones, so a stream
+// flushed after every small message keeps compressing well.
+type Writer struct {
+	w       io.Writer
+	err     error
+	started bool // frame header written
+	closed  bool
+
+	hist    []byte  // up to window bytes already compressed, then the input pending
+	pending int     // start of the pending input in hist
+	table   []int32 // hash of four bytes to one past their latest position in hist
+
+	reps [3]uint32 // the repeat offsets, which carry over between blocks
+
+	lits          []byte
+	seqs          []sequence
+	llc, mlc, ofc []uint8
+	huff          huffEncoder
+	out           []byte
+}
+
+// NewWriter returns a Writer compressing to w.
+func NewWriter(w io.Writer) *Writer {
+	z := &Writer{}
+	z.Reset(w)
+	return z
+}
+
+// Reset discards the Writer's state and makes it compress to w, reusing
+// its buffers.
+func (z *Writer) Reset(w io.Writer) {
+	if z.table == nil {
+		z.table = make([]int32, 1<<hashLog)
+	} else {
+		clear(z.table)
+	}
+	z.w, z.err, z.started, z.closed = w, nil, false, false
+	z.hist, z.pending = z.hist[:0], 0
+	z.reps = [3]uint32{1, 4, 8}
+}
+
+// Write compresses p. Input is held until a block is full or the Writer
+// is flushed or closed.
+func (z *Writer) Write(p []byte) (int, error) {
+	if z.err != nil {
+		return 0, z.err
+	}
+	if z.closed {
+		return 0, errClosed
+	}
+	n := len(p)
+	for len(p) > 0 {
+		k := min(window-(len(z.hist)-z.pending), len(p))
+		z.hist = append(z.hist, p[:k]...)
+		p = p[k:]
+		if len(z.hist)-z.pending == window {
+			if err := z.block(false); err != nil {
+				return n - len(p), err
+			}
+		}
+	}
+	return n, nil
+}
+
+// Flush writes everything written so far as a complete block.
+func (z *Writer) Flush() error {
+	if z.err != nil || z.closed {
+		return z.err
+	}
+	if z.pending == len(z.hist) {
+		return nil
+	}
+	return z.block(false)
+}
+
+// Close writes the rest of the input and ends the frame. It does not close
+// the underlying writer.
+func (z *Writer) Close() error {
+	if z.err != nil || z.closed {
+		return z.err
+	}
+	z.closed = true
+	return z.block(true)
+}
+
+// block writes the pending input as one block and slides the window.
+func (z *Writer) block(last bool) error {
+	z.out = z.out[:0]
+	if !z.started {
+		z.out = append(z.out, header...)
+		z.started = true
+	}
+	z.out = z.encode(z.out, last)
+	z.pending = len(z.hist)
+
+	if drop := z.pending - window; drop > 0 {
+		z.hist = z.hist[:copy(z.hist, z.hist[drop:])]
+		z.pending -= drop
+		for i, v := range z.table {
+			z.table[i] = max(v-int32(drop), 0)
+		}
+	}
+	_, z.err = z.w.Write(z.out)
+	return z.err
+}
+
+// encode appends the pending input as a compressed block, or as a raw one
+// if compressing does not make it smaller.
+func (z *Writer) encode(dst []byte, last bool) []byte {
+	src := z.hist[z.pending:]
+	start := len(dst)
+	dst = append(dst, 0, 0, 0)
+	if len(src) > 0 {
+		reps := z.reps
+		z.parse()
+		dst = z.literals(dst)
+		dst = z.sequences(dst)
+		if size := len(dst) - start - 3; size < len(src) {
+			blockHeader(dst[start:], last, blockCompressed, size)
+			return dst
+		}
+		// The decoder only updates the repeat offsets from compressed
+		// blocks.
+		z.reps = reps
+	}
+	dst = append(dst[:start+3], src...)
+	blockHeader(dst[start:], last, blockRaw, len(src))
+	return dst
+}
+
+func blockHeader(b []byte, last bool, kind, size int) {
+	v := kind<<1 | size<<3
+	if last {
+		v |= 1
+	}
+	b[0], b[1], b[2] = byte(v), byte(v>>8), byte(v>>16)
+}
+
+// parse splits the pending input into sequences and literals. At each
+// position it tries the latest offset, which is the cheapest to code, and
+// then the last position with the same four bytes; a match found there is
+// put off by one byte if the next position has a longer one.
+func (z *Writer) parse() {
+	src, end := z.hist, len(z.hist)
+	z.lits, z.seqs = z.lits[:0], z.seqs[:0]
+	anchor, i := z.pending, z.pending
+	for i+minMatch <= end {
+		cur := binary.LittleEndian.Uint32(src[i:])
+		if rep := int(z.reps[0]); i > anchor && rep <= i && binary.LittleEndian.Uint32(src[i-rep:]) == cur {
+			n := z.extend(i, i-rep, minMatch)
+			z.emit(anchor, i, rep, n)
+			i += n
+			anchor = i
+			continue
+		}
+		cand, n := z.find(i, anchor, cur)
+		if n == 0 {
+			// Step faster through input that does not repeat.
+			i += 1 + (i-anchor)>>6
+			continue
+		}
+		if i+1+minMatch <= end {
+			if cand1, n1 := z.find(i+1, anchor, binary.LittleEndian.Uint32(src[i+1:])); n1 > n {
+				i, cand, n = i+1, cand1, n1
+			}
+		}
+		for i > anchor && cand > 0 && src[i-1] == src[cand-1] {
+			i, cand, n = i-1, cand-1, n+1
+		}
+		z.emit(anchor, i, i-cand, n)
+		i += n
+		anchor = i
+	}
+	z.lits = append(z.lits, src[anchor:]...)
+}
+
+// find looks up the last position with the four bytes cur at i, records
+// i in its place and returns the candidate with the length of the match,
+// zero if there is none.
+func (z *Writer) find(i, anchor int, cur uint32) (cand, n int) {
+	h := cur * 2654435761 >> (32 - hashLog)
+	cand = int(z.table[h]) - 1
+	z.table[h] = int32(i + 1)
+	if cand < 0 || i-cand >= window || binary.LittleEndian.Uint32(z.hist[cand:]) != cur {
+		return 0, 0
+	}
+	return cand, z.extend(i, cand, minMatch)
+}
+
+// extend returns how far the n bytes matched at i and cand go on, up to
+// the end of the input.
+func (z *Writer) extend(i, cand, n int) int {
+	src := z.hist
+	for i+n < len(src) && src[cand+n] == src[i+n] {
+		n++
+	}
+	return n
+}
+
+// emit adds the sequence for a match of n bytes at i, off bytes back, with
+// the literals since anchor. The offset is coded as a repeat offset where
+// one fits, and the repeat offsets are updated as the decoder will.
+func (z *Writer) emit(anchor, i, off, n int) {
+	lit, o, r := uint32(i-anchor), uint32(off), &z.reps
+	var code uint32
+	switch {
+	case lit > 0 && o == r[0]:
+		code = 1
+	case lit > 0 && o == r[1], lit == 0 && o == r[1]:
+		code = 2
+		if lit == 0 {
+			code = 1
+		}
+		r[0], r[1] = r[1], r[0]
+	case lit > 0 && o == r[2], lit == 0 && o == r[2]:
+		code = 3
+		if lit == 0 {
+			code = 2
+		}
+		r[0], r[1], r[2] = r[2], r[0], r[1]
+	case lit == 0 && o == r[0]-1:
+		code = 3
+		r[0], r[1], r[2] = o, r[0], r[1]
+	default:
+		code = o + 3
+		r[0], r[1], r[2] = o, r[0], r[1]
+	}
+	z.lits = append(z.lits, z.hist[anchor:i]...)
+	z.seqs = append(z.seqs, sequence{lit: lit, offset: code, match: uint32(n)})
+}
+
+// literals appends the literals section: Huffman-coded when that is
+// smaller, a single repeated byte, or the bytes as they are.
+func (z *Writer) literals(dst []byte) []byte {
+	lits := z.lits
+	if out, ok := z.huff.literals(dst, lits); ok {
+		return out
+	}
+	if len(lits) > 1 && allSame(lits) {
+		return append(literalsHeader(dst, literalsRLE, len(lits)), lits[0])
+	}
+	return append(literalsHeader(dst, literalsRaw, len(lits)), lits...)
+}
+
+func allSame(b []byte) bool {
+	for _, c := range b[1:] {
+		if c != b[0] {
+			return false
+		}
+	}
+	return true
+}
+
+const (
+	literalsRaw        = 0
+	literalsRLE        = 1
+	literalsCompressed = 2
+)
+
+// literalsHeader appends the header of a raw or RLE literals section.
+func literalsHeader(dst []byte, kind byte, size int) []byte {
+	switch {
+	case size < 1<<5:
+		return append(dst, kind|byte(size)<<3)
+	case size < 1<<12:
+		return append(dst, kind|1<<2|byte(size)<<4, byte(size>>4))
+	default:
+		return append(dst, kind|3<<2|byte(size)<<4, byte(size>>4), byte(size>>12))
+	}
+}
@@ -0,0 +1,125 @@
+package zstd
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand/v2"
+	"os/exec"
+	"testing"
+)
+
+// inputs covers each way a block can be coded: raw, RLE and Huffman
+// literals, with and without matches, and input longer than the window.
+func inputs() map[string][]byte {
+	r := rand.New(rand.NewPCG(1, 2))
+	random := make([]byte, 300_000)
+	for i := range random {
+		random[i] = byte(r.Uint32())
+	}
+	var events bytes.Buffer
+	for i := range 20_000 {
+		fmt.Fprintf(&events, "event: datastar-patch-signals\ndata: signals {\"flow\":{\"nodes\":[{\"id\":\"n%d\",\"x\":%d,\"y\":%.3f}]}}\n\n",
+			i, r.IntN(1000), r.Float64())
+	}
+	high := make([]byte, 5000)
+	for i := range high {
+		high[i] = byte(200 + r.IntN(8))
+	}
+	// A geometric distribution needs codes longer than the format allows.
+	skewed := make([]byte, 200_000)
+	for i := range skewed {
+		for skewed[i] < 40 && r.IntN(2) == 0 {
+			skewed[i]++
+		}
+	}
+	return map[string][]byte{
+		"empty":  nil,
+		"byte":   []byte("a"),
+		"run":    bytes.Repeat([]byte("a"), 100_000),
+		"random": random,
+		"events": events.Bytes(),
+		"high":   high,
+		"skewed": skewed,
+	}
+}
+
+// compress writes data in chunks of the given sizes, in turn, flushing
+// after each if flush is set.
+func compress(data []byte, chunks []int, flush bool) []byte {
+	var buf bytes.Buffer
+	z := NewWriter(&buf)
+	for i := 0; len(data) > 0; i++ {
+		n := len(data)
+		if len(chunks) > 0 {
+			n = min(chunks[i%len(chunks)], n)
+		}
+		z.Write(data[:n])
+		data = data[n:]
+		if flush {
+			z.Flush()
+		}
+	}
+	z.Close()
+	return buf.Bytes()
+}
+
+func TestRoundTrip(t *testing.T) {
+	zstd, err := exec.LookPath("zstd")
+	if err != nil {
+		t.Skip("no zstd command to decompress with")
+	}
+	writes := []struct {
+		name   string
+		chunks []int
+		flush  bool
+	}{
+		{"whole", nil, false},
+		{"flushed", []int{1, 7, 300, 5000}, true},
+		{"events", []int{100}, true},
+		{"blocks", []int{70_000}, false},
+	}
+	for name, data := range inputs() {
+		for _, wr := range writes {
+			t.Run(name+"/"+wr.name, func(t *testing.T) {
+				cmd := exec.Command(zstd, "-d", "-c")
+				cmd.Stdin = bytes.NewReader(compress(data, wr.chunks, wr.flush))
+				got, err := cmd.Output()
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !bytes.Equal(got, data) {
+					t.Errorf("decompressed %d bytes, want %d", len(got), len(data))
+				}
+			})
+		}
+	}
+}
+
+func TestCompresses(t *testing.T) {
+	data := inputs()["events"]
+	if n := len(compress(data, []int{100}, true)); n > len(data)/4 {
+		t.Errorf("%d bytes of events flushed one by one came out at %d", len(data), n)
+	}
+	// Blocks that do not compress are stored as they are.
+	random := inputs()["random"]
+	if n := len(compress(random, nil, false)); n > len(random)+64 {
+		t.Errorf("%d random bytes came out at %d", len(random), n)
+	}
+}
+
+func TestReset(t *testing.T) {
+	data := inputs()["events"][:10_000]
+	want := compress(data, nil, false)
+	var buf bytes.Buffer
+	z := NewWriter(&bytes.Buffer{})
+	z.Write(inputs()["random"][:50_000])
+	z.Reset(&buf)
+	z.Write(data)
+	z.Close()
+	if !bytes.Equal(buf.Bytes(), want) {
+		t.Error("a reset Writer compresses differently from a new one")
+	}
+	if _, err := z.Write(data); err == nil {
+		t.Error("Write after Close succeeded")
+	}
+}
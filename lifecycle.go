@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/yacobolo/datastar-lit-examples/internal/datastar"
+)
+
+// reconnectDelay is how long drained SSE clients wait before reconnecting,
+// long enough for a restarted server to come back up.
+const reconnectDelay = 2 * time.Second
+
+// listenAndServe runs srv until it fails or the process receives SIGINT or
+// SIGTERM. On a signal it tells every open SSE stream to reconnect, then
+// lets in-flight requests finish; both together get up to drain. A second signal kills the
+// process immediately.
+func listenAndServe(srv *http.Server, streams *datastar.Streams, drain time.Duration) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	errc := make(chan error, 1)
+	go func() {
+		if srv.TLSConfig != nil {
+			errc <- srv.ListenAndServeTLS("", "")
+		} else {
+			errc <- srv.ListenAndServe()
+		}
+	}()
+
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+	}
+	stop()
+
+	slog.Info("shutting down", "streams", streams.Len(), "timeout", drain)
+	sctx, cancel := context.WithTimeout(context.Background(), drain)
+	defer cancel()
+	streams.Drain(sctx, reconnectDelay)
+	if err := srv.Shutdown(sctx); err != nil {
+		srv.Close()
+		return fmt.Errorf("shutdown: %w", err)
+	}
+	if err := <-errc; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	slog.Info("shutdown complete")
+	return nil
+}
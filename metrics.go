@@ -0,0 +1,86 @@
+package main
+
+import (
+	"cmp"
+	"net/http"
+	"strconv"
+
+	"github.com/yacobolo/datastar-lit-examples/internal/accesslog"
+	"github.com/yacobolo/datastar-lit-examples/internal/datastar"
+	"github.com/yacobolo/datastar-lit-examples/internal/livereload"
+	"github.com/yacobolo/datastar-lit-examples/internal/metrics"
+)
+
+// serverMetrics are the series served on /metrics.
+type serverMetrics struct {
+	*metrics.Registry
+	routes *http.ServeMux
+
+	requests *metrics.Counter
+	latency  *metrics.Histogram
+	bytes    *metrics.Counter
+	events   *metrics.Counter
+	patches  *metrics.Counter
+	builds   *metrics.Counter
+	reloads  *metrics.Counter
+}
+
+// newServerMetrics registers the server's metrics. Requests are labelled
+// with the routes pattern that matched them rather than their path, which
+// keeps the number of series bounded.
+func newServerMetrics(routes *http.ServeMux, streams *datastar.Streams) *serverMetrics {
+	reg := &metrics.Registry{}
+	m := &serverMetrics{
+		Registry: reg,
+		routes:   routes,
+		requests: reg.NewCounter("http_requests_total", "Requests served, by route pattern, method and status code.", "route", "method", "code"),
+		latency:  reg.NewHistogram("http_request_duration_seconds", "Time to serve a request, by route pattern. SSE requests last as long as the stream.", metrics.DefaultBuckets, "route"),
+		bytes:    reg.NewCounter("http_response_bytes_total", "Response body bytes sent after compression, by route pattern.", "route"),
+		events:   reg.NewCounter("datastar_events_total", "Datastar SSE events sent, by event type.", "type"),
+		patches:  reg.NewCounter("datastar_signal_patches_total", "Signal patches sent, by top-level signal such as flow, scene or chart.", "root"),
+		builds:   reg.NewCounter("livereload_builds_total", "Asset builds in dev mode, by bundle and result.", "bundle", "result"),
+		reloads:  reg.NewCounter("livereload_reloads_total", "Reloads pushed to pages in dev mode: page or css.", "kind"),
+	}
+	reg.NewGaugeFunc("sse_streams_open", "SSE streams currently open.", func() float64 { return float64(streams.Len()) })
+	streams.OnEvent = m.event
+	return m
+}
+
+// request is an accesslog observer.
+func (m *serverMetrics) request(e accesslog.Entry) {
+	_, route := m.routes.Handler(e.Request)
+	route = cmp.Or(route, "unmatched")
+	m.requests.Inc(route, method(e.Request.Method), strconv.Itoa(e.Status))
+	m.latency.Observe(e.Duration.Seconds(), route)
+	m.bytes.Add(float64(e.Bytes), route)
+}
+
+// method is the label for a request method. Clients can send any token
+// as a method, so everything outside RFC 9110's set is counted as "other".
+func method(m string) string {
+	switch m {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+		http.MethodDelete, http.MethodConnect, http.MethodOptions, http.MethodTrace:
+		return m
+	}
+	return "other"
+}
+
+func (m *serverMetrics) event(e datastar.Event) {
+	m.events.Inc(e.Type)
+	for _, root := range e.Roots {
+		m.patches.Inc(root)
+	}
+}
+
+// watch counts the builds and reloads of a dev server.
+func (m *serverMetrics) watch(l *livereload.Reloader) {
+	l.OnBuild = func(bundle string, ok bool) {
+		result := "ok"
+		if !ok {
+			result = "error"
+		}
+		m.builds.Inc(bundle, result)
+	}
+	l.OnReload = func(kind string) { m.reloads.Inc(kind) }
+}
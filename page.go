@@ -0,0 +1,161 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"time"
+
+	"github.com/yacobolo/datastar-lit-examples/internal/fingerprint"
+	"github.com/yacobolo/datastar-lit-examples/internal/flowgraph"
+	"github.com/yacobolo/datastar-lit-examples/internal/importmap"
+	"github.com/yacobolo/datastar-lit-examples/internal/signals"
+	"github.com/yacobolo/datastar-lit-examples/internal/svg"
+)
+
+// signalsFunc picks the state a page request boots with. It is the hook for
+// per-user or per-session state; the default serves signals.Default.
+type signalsFunc func(r *http.Request) (signals.Signals, error)
+
+// errNoState means a signalsFunc has nothing for this request and the next
+// source should be tried.
+var errNoState = errors.New("no state for request")
+
+// pageHandler renders index.html as an html/template with the initial
+// signals injected into data-signals. The template is parsed on every
+// request so edits show up without a restart, like the static files. When
+// the CDN dependencies have been vendored, their URLs are pointed at the
+// local copies first. With versions set, the assets the page references are
+// fingerprinted, and read and hashed again only when one of them changes.
+type pageHandler struct {
+	fsys     fs.FS
+	name     string
+	sources  []signalsFunc
+	dev      bool // include the live reload hook
+	static   bool // leave out what needs the server, for export and render
+	versions *fingerprint.Table
+}
+
+func newPageHandler(fsys fs.FS, sources ...signalsFunc) *pageHandler {
+	return &pageHandler{fsys: fsys, name: "index.html", sources: sources}
+}
+
+func (h *pageHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s, err := h.signals(r)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		http.NotFound(w, r)
+		return
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	body, err := h.page(r.Context(), s)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "render failed", "page", h.name, "err", err)
+		http.Error(w, "render failed", http.StatusInternalServerError)
+		return
+	}
+	sum := sha256.Sum256(body)
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("ETag", `"`+hex.EncodeToString(sum[:])+`"`)
+	http.ServeContent(w, r, h.name, time.Time{}, bytes.NewReader(body))
+}
+
+// page renders the page booted with s and fingerprints its assets.
+func (h *pageHandler) page(ctx context.Context, s signals.Signals) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := h.render(&buf, s); err != nil {
+		return nil, err
+	}
+	if h.versions == nil {
+		return buf.Bytes(), nil
+	}
+	page, err := h.versions.Page(h.fsys, h.name, buf.Bytes())
+	if err != nil {
+		// Plain asset URLs still work, just without long-lived caching.
+		slog.WarnContext(ctx, "fingerprint failed", "page", h.name, "err", err)
+		return buf.Bytes(), nil
+	}
+	return page, nil
+}
+
+func (h *pageHandler) signals(r *http.Request) (signals.Signals, error) {
+	for _, source := range h.sources {
+		s, err := source(r)
+		if errors.Is(err, errNoState) {
+			continue
+		}
+		return s, err
+	}
+	return signals.Default(), nil
+}
+
+func (h *pageHandler) render(w io.Writer, s signals.Signals) error {
+	src, err := fs.ReadFile(h.fsys, h.name)
+	if err != nil {
+		return err
+	}
+	switch m, err := importmap.Load(h.fsys); {
+	case err == nil:
+		src = m.Rewrite(src)
+	case !errors.Is(err, fs.ErrNotExist):
+		return err
+	}
+	tmpl, err := template.New(h.name).Parse(string(src))
+	if err != nil {
+		return err
+	}
+	s.Flow.Errors = flowgraph.Check(s.Flow)
+	// html/template escapes the JSON for the attribute context, so quotes
+	// in labels cannot break out of data-signals.
+	b, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	// The diagram is drawn into the page too, for readers without
+	// JavaScript, crawlers and print.
+	var flow bytes.Buffer
+	if err := svg.Encode(&flow, s.Flow); err != nil {
+		return err
+	}
+	return tmpl.Execute(w, struct {
+		Signals string
+		Dev     bool
+		Static  bool
+		FlowSVG template.HTML
+	}{string(b), h.dev, h.static, template.HTML(flow.String())})
+}
+
+var stateName = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// stateDir serves saved signal documents: ?state=<name> boots the page from
+// <name>.json in fsys.
+func stateDir(fsys fs.FS) signalsFunc {
+	return func(r *http.Request) (signals.Signals, error) {
+		name := r.URL.Query().Get("state")
+		if name == "" {
+			return signals.Signals{}, errNoState
+		}
+		if !stateName.MatchString(name) {
+			return signals.Signals{}, fmt.Errorf("invalid state name %q", name)
+		}
+		b, err := fs.ReadFile(fsys, name+".json")
+		if err != nil {
+			return signals.Signals{}, err
+		}
+		return signals.Decode(b)
+	}
+}
@@ -1,18 +1,230 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
 	"fmt"
+	"io/fs"
 	"log"
+	"log/slog"
 	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/yacobolo/datastar-lit-examples/internal/accesslog"
+	"github.com/yacobolo/datastar-lit-examples/internal/assets"
+	"github.com/yacobolo/datastar-lit-examples/internal/compress"
+	"github.com/yacobolo/datastar-lit-examples/internal/datastar"
+	"github.com/yacobolo/datastar-lit-examples/internal/fingerprint"
+	"github.com/yacobolo/datastar-lit-examples/internal/flowstore"
+	"github.com/yacobolo/datastar-lit-examples/internal/livereload"
+	"github.com/yacobolo/datastar-lit-examples/internal/requestid"
+	"github.com/yacobolo/datastar-lit-examples/internal/signals"
+	"github.com/yacobolo/datastar-lit-examples/internal/static"
 )
 
 func main() {
-	port := "8080"
+	cmd, args := "serve", os.Args[1:]
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		cmd, args = args[0], args[1:]
+	}
+
+	var err error
+	switch cmd {
+	case "serve":
+		err = serve(args, false)
+	case "dev":
+		err = serve(args, true)
+	case "render":
+		err = render(args)
+	case "build":
+		err = build(args)
+	case "vendor":
+		err = vendor(args)
+	case "export":
+		err = export(args)
+	default:
+		err = fmt.Errorf("unknown command %q (want serve, dev, render, build, vendor or export)", cmd)
+	}
+	if errors.Is(err, flag.ErrHelp) {
+		os.Exit(0)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// serve runs the HTTP server. In dev mode it also rebuilds the assets on
+// every change and pushes reloads to open pages, which needs the sources on
+// disk.
+func serve(args []string, dev bool) error {
+	cfg, err := loadConfig(args)
+	if err != nil {
+		return err
+	}
+	if dev {
+		cfg.Disk = true
+	}
+	logger := newLogger(cfg.LogFormat)
+	slog.SetDefault(logger)
+
+	site, states, closeSite, err := openSite(cfg)
+	if err != nil {
+		return err
+	}
+	defer closeSite()
+
+	files, err := static.New(site, cfg.Allow, cfg.Deny)
+	if err != nil {
+		return err
+	}
+
+	mux := http.NewServeMux()
+	streams := datastar.NewStreams()
+	stats := newServerMetrics(mux, streams)
+
+	// Datastar SSE endpoints
+	registerHandlers(mux)
+	registerFlowFiles(mux)
+
+	flows, err := flowstore.Open(cfg.Flows)
+	if err != nil {
+		return err
+	}
+	flowDocs{flows}.register(mux)
+
+	// The page itself is a template so it can boot into different states
+	page := newPageHandler(site, stateDir(states))
+	page.dev = dev
+	if !dev {
+		// Asset URLs carry content hashes and are cached for good. Dev mode
+		// keeps stable names for the live reload's stylesheet swap.
+		page.versions = &fingerprint.Table{}
+		files.Versions = page.versions
+		// Render once so hashed URLs from pages loaded before a restart
+		// resolve right away.
+		if _, err := page.page(context.Background(), signals.Default()); err != nil {
+			slog.Error("render failed", "page", page.name, "err", err)
+		}
+	}
+	mux.Handle("GET /{$}", page)
+	mux.Handle("GET /index.html", page)
+
+	probes := newProbes(site, states, page.versions)
+	probes.addCheck("flows", flows.Check)
+	probes.register(mux)
+
+	if dev {
+		reloader := livereload.New()
+		stats.watch(reloader)
+		stop, err := reloader.Watch(assets.Options{Dir: cfg.Root})
+		if err != nil {
+			return err
+		}
+		defer stop()
+		mux.Handle("GET /api/dev/reload", reloader)
+	}
+
+	// Everything else comes from the allowlisted static files
+	mux.Handle("/", files)
+
+	if cfg.AdminAddr != "" {
+		stopAdmin, err := startAdmin(cfg.AdminAddr, streams, stats)
+		if err != nil {
+			return err
+		}
+		defer stopAdmin()
+	} else {
+		// Without an admin listener the metrics stay on the public port.
+		mux.Handle("GET /metrics", stats)
+	}
+
+	tlsCfg, err := tlsConfig(cfg)
+	if err != nil {
+		return err
+	}
+
+	srv := &http.Server{
+		TLSConfig:         tlsCfg,
+		Addr:              cfg.listenAddr(),
+		Handler:           accesslog.Handler(logger, streams.Handler(compress.Handler(mux)), stats.request),
+		ErrorLog:          slog.NewLogLogger(logger.Handler(), slog.LevelError),
+		ReadHeaderTimeout: time.Duration(cfg.ReadTimeout),
+		ReadTimeout:       time.Duration(cfg.ReadTimeout),
+		WriteTimeout:      time.Duration(cfg.WriteTimeout),
+		IdleTimeout:       time.Duration(cfg.IdleTimeout),
+	}
+
+	slog.Info("serving", "url", cfg.url())
+	return listenAndServe(srv, streams, time.Duration(cfg.ShutdownTimeout))
+}
+
+// newLogger returns the server's logger, writing text or JSON records to
+// stderr. Records logged with a request's context carry its ID.
+func newLogger(format string) *slog.Logger {
+	var h slog.Handler = slog.NewTextHandler(os.Stderr, nil)
+	if format == "json" {
+		h = slog.NewJSONHandler(os.Stderr, nil)
+	}
+	return slog.New(requestid.LogHandler(h))
+}
+
+// openSite returns the document root and saved states, from the binary when
+// it was built with -tags embed and from disk otherwise or with -disk.
+func openSite(cfg config) (site, states fs.FS, release func() error, err error) {
+	if embedded, ok := embeddedSite(); ok && !cfg.Disk {
+		states, err := fs.Sub(embedded, "states")
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		slog.Info("serving embedded site")
+		return embedded, states, func() error { return nil }, nil
+	}
+
+	// os.Root keeps symlinks from reaching outside the document root
+	root, err := os.OpenRoot(cfg.Root)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	slog.Info("serving from disk", "root", cfg.Root)
+	return root.FS(), os.DirFS(cfg.States), root.Close, nil
+}
+
+// render writes index.html with its initial signals filled in, for hosting
+// the page without the Go server.
+func render(args []string) error {
+	fset := flag.NewFlagSet("render", flag.ExitOnError)
+	out := fset.String("o", "", "write the page to `file` instead of stdout")
+	state := fset.String("state", "", "boot from the signals in `file` instead of the defaults")
+	if err := fset.Parse(args); err != nil {
+		return err
+	}
 
-	// Serve static files from current directory
-	fs := http.FileServer(http.Dir("."))
-	http.Handle("/", fs)
+	s := signals.Default()
+	if *state != "" {
+		b, err := os.ReadFile(*state)
+		if err != nil {
+			return err
+		}
+		if s, err = signals.Decode(b); err != nil {
+			return fmt.Errorf("%s: %w", *state, err)
+		}
+	}
 
-	fmt.Printf("Serving at http://localhost:%s\n", port)
-	log.Fatal(http.ListenAndServe(":"+port, nil))
+	page := newPageHandler(os.DirFS("."))
+	page.static = true
+	if *out == "" {
+		return page.render(os.Stdout, s)
+	}
+	f, err := os.Create(*out)
+	if err != nil {
+		return err
+	}
+	if err := page.render(f, s); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
 }
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"crypto/tls"
+	"log/slog"
+	"net"
+	"slices"
+
+	"github.com/yacobolo/datastar-lit-examples/internal/devcert"
+)
+
+// tlsConfig returns the server's TLS settings, or nil to serve plain HTTP.
+// net/http negotiates HTTP/2 over TLS on its own, which lifts the
+// six-connections-per-origin limit that long-lived SSE streams run into.
+func tlsConfig(cfg config) (*tls.Config, error) {
+	var cert tls.Certificate
+	switch {
+	case cfg.TLSCert != "":
+		var err error
+		if cert, err = tls.LoadX509KeyPair(cfg.TLSCert, cfg.TLSKey); err != nil {
+			return nil, err
+		}
+	case cfg.TLSSelfSigned:
+		dir, err := devcert.CacheDir()
+		if err != nil {
+			return nil, err
+		}
+		hosts := devcert.DefaultHosts
+		if ip := net.ParseIP(cfg.Addr); cfg.Addr != "" && (ip == nil || !ip.IsUnspecified()) {
+			// Also cover the bound interface, e.g. a LAN address.
+			hosts = append(slices.Clone(hosts), cfg.Addr)
+		}
+		if cert, err = devcert.Load(dir, hosts); err != nil {
+			return nil, err
+		}
+		slog.Info("using self-signed certificate; your browser will ask you to trust it", "dir", dir)
+	default:
+		return nil, nil
+	}
+	return &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		Certificates: []tls.Certificate{cert},
+	}, nil
+}
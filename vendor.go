@@ -0,0 +1,40 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/yacobolo/datastar-lit-examples/internal/importmap"
+)
+
+// vendor copies the page's CDN dependencies into demo/dist/vendor so the
+// server can serve the page without network access.
+func vendor(args []string) error {
+	fset := flag.NewFlagSet("vendor", flag.ExitOnError)
+	dir := fset.String("dir", ".", "repository root `dir` containing index.html and node_modules")
+	cache := fset.String("cache", "", "fall back to the CDN mirror in `dir`, laid out as <host>/<path>")
+	minify := fset.Bool("minify", true, "minify output")
+	if err := fset.Parse(args); err != nil {
+		return err
+	}
+
+	html, err := os.ReadFile(filepath.Join(*dir, "index.html"))
+	if err != nil {
+		return err
+	}
+	page, err := importmap.Parse(html)
+	if err != nil {
+		return err
+	}
+	entries, err := importmap.Vendor(page, importmap.Options{Dir: *dir, Cache: *cache, Minify: *minify})
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		fmt.Printf("%s\n  -> %s (from %s)\n", e.URL, e.File, e.From)
+	}
+	fmt.Printf("Vendored %d dependencies, manifest at %s\n", len(entries), importmap.ManifestFile)
+	return nil
+}